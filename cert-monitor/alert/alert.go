@@ -21,15 +21,25 @@ package alert
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 )
 
-// ObjectRef contains the information to locate the object in k8s, namely its name and namespace
+// ObjectRef contains the information to locate the object in k8s, namely its name and namespace, along with its kind,
+// API version and UID when known
 type ObjectRef struct {
 	// Name is the name of the k8s object
 	Name string `json:"name"`
 	// Namespace is the namespace of the k8s object
 	Namespace string `json:"namespace"`
+	// Kind is the kind of the k8s object
+	Kind string `json:"kind,omitempty"`
+	// APIVersion is the API version of the k8s object
+	APIVersion string `json:"apiVersion,omitempty"`
+	// UID is the UID of the k8s object
+	UID string `json:"uid,omitempty"`
 }
 
 // Level defines the level of an alert, it is an enum of UNKNOWN, INFO, WARN, ERROR
@@ -86,7 +96,32 @@ func (l *Level) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// Alert contains the information about the alert
+// ParseLevel returns the Level of its string representation
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "INFO":
+		return Info, nil
+	case "WARN":
+		return Warn, nil
+	case "ERROR":
+		return Error, nil
+	default:
+		return Unknown, fmt.Errorf("unknown level %q", s)
+	}
+}
+
+// UnmarshalText unmarshals the string representation of the level, unlike UnmarshalJSON an unknown level is rejected
+func (l *Level) UnmarshalText(text []byte) error {
+	val, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = val
+	return nil
+}
+
+// Alert contains the information about the alert. The pinot tags define the type of the Pinot columns, the fields
+// are dimensions unless tagged as metric or dateTime with its format and granularity.
 type Alert struct {
 	// Level defines the level of the alert
 	Level Level `json:"level"`
@@ -97,7 +132,64 @@ type Alert struct {
 	// Source defines the source of the alert
 	Source string `json:"source"`
 	// When defines when the alert has been created
-	When int64 `json:"when"`
+	When int64 `json:"when" pinot:"dateTime,1:NANOSECONDS:EPOCH,1:MINUTES"`
+	// Expiration defines when the certificate expires in nanoseconds since the epoch
+	Expiration int64 `json:"expiration,omitempty" pinot:"metric"`
+	// RemainingSeconds defines the number of seconds until the certificate expires when the alert is created, it is
+	// negative once the certificate is expired
+	RemainingSeconds int64 `json:"remainingSeconds,omitempty" pinot:"metric"`
+	// Certificate contains the metadata of the certificate when known
+	Certificate *CertificateMetadata `json:"certificate,omitempty"`
+	// Labels contains the labels of the alert, e.g. the team or the environment, used to route and query the alerts.
+	// The keys being free-form, they are not derived as Pinot columns. The keys are named with LabelName.
+	Labels map[string]string `json:"labels,omitempty" pinot:"-"`
+	// Annotations contains the annotations of the alert, e.g. a runbook URL, the keys are named with LabelName
+	Annotations map[string]string `json:"annotations,omitempty" pinot:"-"`
+}
+
+// labelNameReplacer replaces the separators of the prefixed keys, the dot nests the fields of the alerts and names the
+// Pinot columns, e.g. labels.team, and the slash is not supported in the Pinot columns
+var labelNameReplacer = strings.NewReplacer(".", "_", "/", "_")
+
+// LabelName returns the name of the k8s label or annotation key in the labels and annotations of the alerts, the dots
+// and slashes are replaced by underscores, e.g. app.kubernetes.io/name becomes app_kubernetes_io_name
+func LabelName(key string) string {
+	return labelNameReplacer.Replace(key)
+}
+
+// CertificateMetadata contains the metadata of a certificate, from its cert-manager resource and from the X.509
+// certificate stored in its secret
+type CertificateMetadata struct {
+	// IssuerName is the name of the issuer of the certificate
+	IssuerName string `json:"issuerName,omitempty"`
+	// IssuerKind is the kind of the issuer, e.g. Issuer or ClusterIssuer
+	IssuerKind string `json:"issuerKind,omitempty"`
+	// SecretName is the name of the secret storing the certificate
+	SecretName string `json:"secretName,omitempty"`
+	// DNSNames contains the DNS names of the certificate
+	DNSNames []string `json:"dnsNames,omitempty"`
+	// SerialNumber is the serial number of the certificate in hexadecimal
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// FingerprintSHA256 is the SHA-256 fingerprint of the DER encoded certificate in hexadecimal
+	FingerprintSHA256 string `json:"fingerprintSha256,omitempty"`
+	// KeyAlgorithm is the algorithm of the public key, e.g. RSA, ECDSA or Ed25519
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+	// KeySize is the size of the public key in bits
+	KeySize int `json:"keySize,omitempty"`
+	// NotBefore defines when the certificate becomes valid in nanoseconds since the epoch
+	NotBefore int64 `json:"notBefore,omitempty"`
+	// NotAfter defines when the certificate expires in nanoseconds since the epoch
+	NotAfter int64 `json:"notAfter,omitempty"`
+}
+
+// incidentKey identifies the certificate of the alert in the incident management systems, so all the alerts of a
+// certificate are grouped in the same incident
+func incidentKey(cluster string, alert Alert) string {
+	key := alert.ObjectRef.Namespace + "/" + alert.ObjectRef.Name
+	if cluster != "" {
+		key = cluster + "/" + key
+	}
+	return "cert-monitor/" + key
 }
 
 // Notifier is responsible to send an alert to an external system
@@ -106,4 +198,18 @@ type Notifier interface {
 	Send(alert Alert) error
 	// Close closes the notifier
 	Close() error
-}
\ No newline at end of file
+}
+
+// BatchNotifier is a Notifier able to send several alerts at once to the external system
+type BatchNotifier interface {
+	Notifier
+	// SendBatch sends all the alerts to the external system
+	SendBatch(alerts []Alert) error
+}
+
+// AsyncNotifier is a Notifier delivering the alerts asynchronously, Send returns before the alert is delivered
+type AsyncNotifier interface {
+	Notifier
+	// Flush waits until all the alerts sent so far are delivered, it returns the delivery failures since the last flush
+	Flush(ctx context.Context) error
+}
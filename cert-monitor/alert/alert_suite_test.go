@@ -20,6 +20,9 @@
 package alert_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -30,3 +33,23 @@ func TestAlert(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Alert Suite")
 }
+
+// tempDir is the directory of the files written by the tests, GinkgoT().TempDir() is a no-op in ginkgo v1
+var tempDir string
+
+var _ = BeforeSuite(func() {
+	var err error
+	tempDir, err = ioutil.TempDir("", "alert")
+	Expect(err).ShouldNot(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	Expect(os.RemoveAll(tempDir)).Should(Succeed())
+})
+
+// writeTempFile writes the data in a file of the temporary directory and returns its path
+func writeTempFile(name string, data []byte) string {
+	path := filepath.Join(tempDir, name)
+	Expect(ioutil.WriteFile(path, data, 0600)).Should(Succeed())
+	return path
+}
@@ -131,6 +131,25 @@ var _ = Describe("Level", func() {
 		})
 	})
 
+	Describe("UnmarshalText", func() {
+		When("level is WARN", func() {
+			It("should return Warn", func() {
+				level := alert.Level(255)
+				err := level.UnmarshalText([]byte("WARN"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(level).Should(Equal(alert.Warn))
+			})
+		})
+
+		When("level is whatever", func() {
+			It("should return an error", func() {
+				level := alert.Level(255)
+				err := level.UnmarshalText([]byte("something"))
+				Expect(err).Should(MatchError(`unknown level "something"`))
+			})
+		})
+	})
+
 	Describe("UnmarshalJSON", func() {
 		When("level is explicitly UNKNOWN", func() {
 			It("should return Unknown", func() {
@@ -0,0 +1,154 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AlertmanagerAlertName is the name of the alerts posted to Alertmanager
+const AlertmanagerAlertName = "CertificateExpiration"
+
+// alertmanagerSeverities are the severities of the firing alerts, an alert is posted as resolved for the severities
+// not matching the level of the alert
+var alertmanagerSeverities = []string{"warning", "critical"}
+
+// NewAlertmanagerNotifier returns a BatchNotifier that posts the alerts to the Alertmanager API v2. An alert fires with
+// the severity of its level and is resolved for the other severities, INFO alerts resolve all the severities.
+func NewAlertmanagerNotifier(cfg AlertmanagerConfig) (BatchNotifier, error) {
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Alertmanager client: %w", err)
+	}
+	return &alertmanagerNotifier{
+		cfg:    cfg,
+		url:    strings.TrimSuffix(cfg.URL, "/") + "/api/v2/alerts",
+		client: client,
+	}, nil
+}
+
+type alertmanagerNotifier struct {
+	cfg    AlertmanagerConfig
+	url    string
+	client *httpClient
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     string            `json:"startsAt"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Send implements Notifier contract
+func (a *alertmanagerNotifier) Send(alert Alert) error {
+	return a.SendBatch([]Alert{alert})
+}
+
+// SendBatch implements BatchNotifier contract, all the alerts are posted in a single request
+func (a *alertmanagerNotifier) SendBatch(alerts []Alert) error {
+	var amAlerts []alertmanagerAlert
+	for _, alert := range alerts {
+		amAlerts = append(amAlerts, a.convert(alert)...)
+	}
+	body, err := json.Marshal(amAlerts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Alertmanager alerts: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Alertmanager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := a.client.do(req); err != nil {
+		return fmt.Errorf("failed to post Alertmanager alerts: %w", err)
+	}
+	return nil
+}
+
+// Close implements Notifier contract
+func (a *alertmanagerNotifier) Close() error {
+	return nil
+}
+
+// convert returns the Alertmanager alerts for each severity, the alert fires for the severity of its level and is
+// resolved for the others
+func (a *alertmanagerNotifier) convert(alert Alert) []alertmanagerAlert {
+	when := time.Unix(0, alert.When).UTC()
+	firing := alertmanagerSeverity(alert.Level)
+	amAlerts := make([]alertmanagerAlert, 0, len(alertmanagerSeverities))
+	for _, severity := range alertmanagerSeverities {
+		amAlert := alertmanagerAlert{
+			Labels: map[string]string{
+				"alertname":   AlertmanagerAlertName,
+				"namespace":   alert.ObjectRef.Namespace,
+				"certificate": alert.ObjectRef.Name,
+				"severity":    severity,
+			},
+			Annotations: map[string]string{
+				"message": alert.Message,
+				"expiry":  expiry(alert),
+			},
+			StartsAt:     when.Format(time.RFC3339),
+			GeneratorURL: a.cfg.GeneratorURL,
+		}
+		if a.cfg.Cluster != "" {
+			amAlert.Labels["cluster"] = a.cfg.Cluster
+		}
+		// the labels and annotations of the alert cannot override the ones identifying the alert
+		addMissing(amAlert.Labels, alert.Labels)
+		addMissing(amAlert.Annotations, alert.Annotations)
+		if severity != firing {
+			amAlert.EndsAt = when.Format(time.RFC3339)
+		} else if a.cfg.ResolveTimeout > 0 {
+			amAlert.EndsAt = when.Add(a.cfg.ResolveTimeout).Format(time.RFC3339)
+		}
+		amAlerts = append(amAlerts, amAlert)
+	}
+	return amAlerts
+}
+
+// addMissing adds the entries of from whose key is not yet in to
+func addMissing(to, from map[string]string) {
+	for k, v := range from {
+		if _, found := to[k]; !found {
+			to[k] = v
+		}
+	}
+}
+
+// alertmanagerSeverity returns the severity label of the firing alert for the level, an empty string when the level
+// does not fire
+func alertmanagerSeverity(level Level) string {
+	switch level {
+	case Error:
+		return "critical"
+	case Warn:
+		return "warning"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alertmanager", func() {
+	var (
+		server *httptest.Server
+		path   string
+		body   []byte
+
+		cfg      alert.AlertmanagerConfig
+		notifier alert.BatchNotifier
+		alerts   []alert.Alert
+		err      error
+
+		now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			body, _ = ioutil.ReadAll(r.Body)
+		}))
+		cfg = alert.AlertmanagerConfig{
+			URL:            server.URL + "/",
+			Cluster:        "prod",
+			ResolveTimeout: 5 * time.Minute,
+		}
+		alerts = []alert.Alert{
+			{
+				Level:      alert.Error,
+				Message:    "certificate expired",
+				ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+				When:       now.UnixNano(),
+				Expiration: now.Add(-time.Hour).UnixNano(),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		notifier, err = alert.NewAlertmanagerNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+		err = notifier.SendBatch(alerts)
+	})
+
+	When("certificate is expired", func() {
+		It("should fire a critical alert and resolve the warning", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(path).Should(Equal("/api/v2/alerts"))
+			Expect(body).Should(MatchJSON(`[
+				{
+					"labels": {"alertname": "CertificateExpiration", "namespace": "ns", "certificate": "cert", "severity": "warning", "cluster": "prod"},
+					"annotations": {"message": "certificate expired", "expiry": "2021-12-31T23:00:00Z"},
+					"startsAt": "2022-01-01T00:00:00Z",
+					"endsAt": "2022-01-01T00:00:00Z"
+				},
+				{
+					"labels": {"alertname": "CertificateExpiration", "namespace": "ns", "certificate": "cert", "severity": "critical", "cluster": "prod"},
+					"annotations": {"message": "certificate expired", "expiry": "2021-12-31T23:00:00Z"},
+					"startsAt": "2022-01-01T00:00:00Z",
+					"endsAt": "2022-01-01T00:05:00Z"
+				}
+			]`))
+		})
+	})
+
+	When("alert has labels and annotations", func() {
+		BeforeEach(func() {
+			alerts[0].Labels = map[string]string{"team": "payments", "severity": "low"}
+			alerts[0].Annotations = map[string]string{"runbook": "https://runbook"}
+		})
+		It("should add them to the Alertmanager alerts without overriding the identifying ones", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			var amAlerts []struct {
+				Labels      map[string]string `json:"labels"`
+				Annotations map[string]string `json:"annotations"`
+			}
+			Expect(json.Unmarshal(body, &amAlerts)).Should(Succeed())
+			Expect(amAlerts).Should(HaveLen(2))
+			Expect(amAlerts[1].Labels).Should(HaveKeyWithValue("team", "payments"))
+			Expect(amAlerts[1].Labels).Should(HaveKeyWithValue("severity", "critical"))
+			Expect(amAlerts[1].Annotations).Should(HaveKeyWithValue("runbook", "https://runbook"))
+		})
+	})
+
+	When("certificate is valid again", func() {
+		BeforeEach(func() {
+			alerts[0].Level = alert.Info
+		})
+		It("should resolve all the severities", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			var amAlerts []map[string]interface{}
+			Expect(json.Unmarshal(body, &amAlerts)).Should(Succeed())
+			Expect(amAlerts).Should(HaveLen(2))
+			for _, amAlert := range amAlerts {
+				Expect(amAlert).Should(HaveKeyWithValue("endsAt", "2022-01-01T00:00:00Z"))
+			}
+		})
+	})
+
+	When("resolve timeout is not set", func() {
+		BeforeEach(func() {
+			cfg.ResolveTimeout = 0
+			alerts[0].Level = alert.Warn
+		})
+		It("should let Alertmanager resolve the firing alert", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			var amAlerts []map[string]interface{}
+			Expect(json.Unmarshal(body, &amAlerts)).Should(Succeed())
+			Expect(amAlerts[0]).ShouldNot(HaveKey("endsAt"))
+			Expect(amAlerts[1]).Should(HaveKey("endsAt"))
+		})
+	})
+})
@@ -0,0 +1,205 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AvroSchema is the Avro schema of the alerts
+const AvroSchema = `{
+  "type": "record",
+  "name": "Alert",
+  "namespace": "io.certmonitor",
+  "fields": [
+    {"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["UNKNOWN", "INFO", "WARN", "ERROR"]}},
+    {"name": "message", "type": "string"},
+    {"name": "objectRef", "type": {"type": "record", "name": "ObjectRef", "fields": [
+      {"name": "name", "type": "string"},
+      {"name": "namespace", "type": "string"},
+      {"name": "kind", "type": "string", "default": ""},
+      {"name": "apiVersion", "type": "string", "default": ""},
+      {"name": "uid", "type": "string", "default": ""}
+    ]}},
+    {"name": "source", "type": "string"},
+    {"name": "when", "type": "long"},
+    {"name": "expiration", "type": "long", "default": 0},
+    {"name": "remainingSeconds", "type": "long", "default": 0},
+    {"name": "certificate", "type": ["null", {"type": "record", "name": "CertificateMetadata", "fields": [
+      {"name": "issuerName", "type": "string", "default": ""},
+      {"name": "issuerKind", "type": "string", "default": ""},
+      {"name": "secretName", "type": "string", "default": ""},
+      {"name": "dnsNames", "type": {"type": "array", "items": "string"}, "default": []},
+      {"name": "serialNumber", "type": "string", "default": ""},
+      {"name": "fingerprintSha256", "type": "string", "default": ""},
+      {"name": "keyAlgorithm", "type": "string", "default": ""},
+      {"name": "keySize", "type": "long", "default": 0},
+      {"name": "notBefore", "type": "long", "default": 0},
+      {"name": "notAfter", "type": "long", "default": 0}
+    ]}], "default": null},
+    {"name": "labels", "type": {"type": "map", "values": "string"}, "default": {}},
+    {"name": "annotations", "type": {"type": "map", "values": "string"}, "default": {}}
+  ]
+}`
+
+// confluentMagicByte is the first byte of a message using the Confluent wire format
+const confluentMagicByte = 0
+
+// NewAvroEncoder returns an Encoder that encodes the alerts in Avro with the Confluent wire format: a magic byte, the
+// schema ID as found in the schema registry for the subject, then the Avro binary payload. The schema is registered
+// when autoRegister is set and the schema is not yet known by the registry.
+func NewAvroEncoder(registry SchemaRegistryClient, subject string, autoRegister bool) Encoder {
+	return &avroEncoder{
+		registry:     registry,
+		subject:      subject,
+		autoRegister: autoRegister,
+	}
+}
+
+type avroEncoder struct {
+	registry     SchemaRegistryClient
+	subject      string
+	autoRegister bool
+
+	mu       sync.Mutex
+	schemaID *int
+}
+
+// Encode implements Encoder contract
+func (a *avroEncoder) Encode(alert Alert) ([]byte, error) {
+	id, err := a.resolveSchemaID()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer([]byte{confluentMagicByte})
+	idBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(idBytes, uint32(id))
+	buf.Write(idBytes)
+
+	level := alert.Level
+	if level > Error {
+		level = Unknown
+	}
+	writeAvroLong(buf, int64(level))
+	writeAvroString(buf, alert.Message)
+	writeAvroString(buf, alert.ObjectRef.Name)
+	writeAvroString(buf, alert.ObjectRef.Namespace)
+	writeAvroString(buf, alert.ObjectRef.Kind)
+	writeAvroString(buf, alert.ObjectRef.APIVersion)
+	writeAvroString(buf, alert.ObjectRef.UID)
+	writeAvroString(buf, alert.Source)
+	writeAvroLong(buf, alert.When)
+	writeAvroLong(buf, alert.Expiration)
+	writeAvroLong(buf, alert.RemainingSeconds)
+	// the certificate is a union of null, index 0, and the metadata record, index 1
+	if cert := alert.Certificate; cert == nil {
+		writeAvroLong(buf, 0)
+	} else {
+		writeAvroLong(buf, 1)
+		writeAvroString(buf, cert.IssuerName)
+		writeAvroString(buf, cert.IssuerKind)
+		writeAvroString(buf, cert.SecretName)
+		writeAvroStrings(buf, cert.DNSNames)
+		writeAvroString(buf, cert.SerialNumber)
+		writeAvroString(buf, cert.FingerprintSHA256)
+		writeAvroString(buf, cert.KeyAlgorithm)
+		writeAvroLong(buf, int64(cert.KeySize))
+		writeAvroLong(buf, cert.NotBefore)
+		writeAvroLong(buf, cert.NotAfter)
+	}
+	writeAvroMap(buf, alert.Labels)
+	writeAvroMap(buf, alert.Annotations)
+	return buf.Bytes(), nil
+}
+
+// ContentType implements Encoder contract
+func (a *avroEncoder) ContentType() string {
+	return "application/vnd.confluent.avro"
+}
+
+// resolveSchemaID returns the ID of the schema, the registry is only called until the ID is found
+func (a *avroEncoder) resolveSchemaID() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.schemaID != nil {
+		return *a.schemaID, nil
+	}
+	id, err := a.registry.LookupSchema(a.subject, SchemaTypeAvro, AvroSchema)
+	if errors.Is(err, ErrSchemaNotFound) && a.autoRegister {
+		id, err = a.registry.RegisterSchema(a.subject, SchemaTypeAvro, AvroSchema)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve schema ID for subject %s: %w", a.subject, err)
+	}
+	a.schemaID = &id
+	return id, nil
+}
+
+// writeAvroLong writes a long using the zig-zag variable length encoding
+func writeAvroLong(buf *bytes.Buffer, v int64) {
+	data := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(data, v)
+	buf.Write(data[:n])
+}
+
+// writeAvroString writes the length of the string followed by its UTF-8 bytes
+func writeAvroString(buf *bytes.Buffer, s string) {
+	writeAvroLong(buf, int64(len(s)))
+	buf.WriteString(s)
+}
+
+// writeAvroStrings writes an array of strings as a single block followed by the empty block ending the array
+func writeAvroStrings(buf *bytes.Buffer, values []string) {
+	if len(values) > 0 {
+		writeAvroLong(buf, int64(len(values)))
+		for _, v := range values {
+			writeAvroString(buf, v)
+		}
+	}
+	writeAvroLong(buf, 0)
+}
+
+// writeAvroMap writes a map of strings as a single block of entries sorted by key followed by the empty block ending
+// the map
+func writeAvroMap(buf *bytes.Buffer, values map[string]string) {
+	if len(values) > 0 {
+		writeAvroLong(buf, int64(len(values)))
+		for _, k := range sortedKeys(values) {
+			writeAvroString(buf, k)
+			writeAvroString(buf, values[k])
+		}
+	}
+	writeAvroLong(buf, 0)
+}
+
+// sortedKeys returns the keys of the map sorted, so the maps are encoded deterministically
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Avro", func() {
+	var (
+		registry   *httptest.Server
+		registered bool
+		lookups    int
+
+		autoRegister bool
+		encoder      alert.Encoder
+
+		a = alert.Alert{
+			Level:      alert.Warn,
+			Message:    "msg",
+			ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns", Kind: "Certificate"},
+			Source:     "host",
+			When:       1,
+			Expiration: 3,
+		}
+	)
+
+	BeforeEach(func() {
+		registered = false
+		lookups = 0
+		autoRegister = false
+		registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).Should(Equal(http.MethodPost))
+			var req map[string]string
+			Expect(json.NewDecoder(r.Body).Decode(&req)).Should(Succeed())
+			Expect(req["schema"]).Should(Equal(alert.AvroSchema))
+			Expect(req["schemaType"]).Should(Equal("AVRO"))
+			switch r.URL.Path {
+			case "/subjects/topic-value":
+				lookups++
+				if !registered {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"subject":"topic-value","id":7,"version":1}`))
+			case "/subjects/topic-value/versions":
+				registered = true
+				_, _ = w.Write([]byte(`{"id":7}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error_code":50001,"message":"unexpected path"}`))
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		registry.Close()
+	})
+
+	JustBeforeEach(func() {
+		encoder = alert.NewAvroEncoder(alert.NewSchemaRegistryClient(alert.SchemaRegistryConfig{
+			URL: registry.URL,
+		}), "topic-value", autoRegister)
+	})
+
+	Describe("Encode", func() {
+		When("schema is registered", func() {
+			BeforeEach(func() {
+				registered = true
+			})
+			It("should use the Confluent wire format", func() {
+				data, err := encoder.Encode(a)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(data).Should(Equal([]byte{
+					0, 0, 0, 0, 7, // magic byte and schema ID
+					4,                // WARN
+					6, 'm', 's', 'g', // message
+					8, 'c', 'e', 'r', 't', // objectRef.name
+					4, 'n', 's', // objectRef.namespace
+					22, 'C', 'e', 'r', 't', 'i', 'f', 'i', 'c', 'a', 't', 'e', // objectRef.kind
+					0,                     // objectRef.apiVersion
+					0,                     // objectRef.uid
+					8, 'h', 'o', 's', 't', // source
+					2, // when
+					6, // expiration
+					0, // remainingSeconds
+					0, // certificate is null
+					0, // labels
+					0, // annotations
+				}))
+			})
+			It("should encode the certificate metadata", func() {
+				withMetadata := a
+				withMetadata.RemainingSeconds = -1
+				withMetadata.Certificate = &alert.CertificateMetadata{
+					IssuerName: "ca",
+					DNSNames:   []string{"a", "b"},
+					KeySize:    256,
+					NotAfter:   3,
+				}
+				data, err := encoder.Encode(withMetadata)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(data[len(data)-23:]).Should(Equal([]byte{
+					6,           // expiration
+					1,           // remainingSeconds
+					2,           // certificate is the metadata record
+					4, 'c', 'a', // issuerName
+					0,                    // issuerKind
+					0,                    // secretName
+					4, 2, 'a', 2, 'b', 0, // dnsNames
+					0,      // serialNumber
+					0,      // fingerprintSha256
+					0,      // keyAlgorithm
+					128, 4, // keySize
+					0, // notBefore
+					6, // notAfter
+					0, // labels
+					0, // annotations
+				}))
+			})
+			It("should encode the labels and annotations sorted by key", func() {
+				withLabels := a
+				withLabels.Labels = map[string]string{"team": "a", "env": "b"}
+				withLabels.Annotations = map[string]string{"url": "c"}
+				data, err := encoder.Encode(withLabels)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(data[len(data)-24:]).Should(Equal([]byte{
+					0,                        // certificate is null
+					4,                        // 2 labels
+					6, 'e', 'n', 'v', 2, 'b', // env
+					8, 't', 'e', 'a', 'm', 2, 'a', // team
+					0,                           // end of labels
+					2, 6, 'u', 'r', 'l', 2, 'c', // url
+					0, // end of annotations
+				}))
+			})
+			It("should look up the schema only once", func() {
+				_, err := encoder.Encode(a)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, err = encoder.Encode(a)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(lookups).Should(Equal(1))
+			})
+		})
+
+		When("schema is not registered", func() {
+			It("should return an error", func() {
+				_, err := encoder.Encode(a)
+				Expect(err).Should(MatchError("failed to resolve schema ID for subject topic-value: schema not found"))
+			})
+		})
+
+		When("schema is not registered and auto registration is enabled", func() {
+			BeforeEach(func() {
+				autoRegister = true
+			})
+			It("should register the schema", func() {
+				data, err := encoder.Encode(a)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(registered).Should(BeTrue())
+				Expect(data[:5]).Should(Equal([]byte{0, 0, 0, 0, 7}))
+			})
+		})
+
+		When("registry client wraps the schema not found error", func() {
+			It("should register the schema", func() {
+				encoder := alert.NewAvroEncoder(wrappingRegistry{}, "topic-value", true)
+				data, err := encoder.Encode(a)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(data[:5]).Should(Equal([]byte{0, 0, 0, 0, 9}))
+			})
+		})
+	})
+})
+
+// wrappingRegistry is a SchemaRegistryClient that wraps ErrSchemaNotFound
+type wrappingRegistry struct{}
+
+func (wrappingRegistry) LookupSchema(subject, _, _ string) (int, error) {
+	return 0, fmt.Errorf("failed to look up subject %s: %w", subject, alert.ErrSchemaNotFound)
+}
+
+func (wrappingRegistry) RegisterSchema(_, _, _ string) (int, error) {
+	return 9, nil
+}
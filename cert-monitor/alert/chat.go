@@ -0,0 +1,224 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// maxDigestLines is the maximum number of alerts listed in a digest message
+const maxDigestLines = 30
+
+// chatMessage is the content of a chat message about an alert or a digest of alerts, each chat notifier renders it
+// with the markup of its service
+type chatMessage struct {
+	// Level is the level of the alert, the highest level of the alerts for a digest
+	Level Level
+	// Title is the title of the message, without the level
+	Title string
+	// Facts are the details of the certificate
+	Facts []chatFact
+	// Lines lists the alerts of a digest
+	Lines []chatLine
+	// More is the number of alerts of a digest not listed in the lines
+	More int
+	// URL is the URL of the dashboard
+	URL string
+}
+
+// Digest returns whether the message is a digest of alerts
+func (m chatMessage) Digest() bool {
+	return len(m.Lines) > 0
+}
+
+// Text returns the plain text summary of the message
+func (m chatMessage) Text() string {
+	if m.Digest() {
+		return m.Title
+	}
+	return fmt.Sprintf("%s %s", m.Level, m.Title)
+}
+
+// chatFact is a named detail of a certificate
+type chatFact struct {
+	Name  string
+	Value string
+}
+
+// chatLine is a line of a digest
+type chatLine struct {
+	Level Level
+	Text  string
+}
+
+// chatFormatter formats the alerts in chat messages and posts them with the digest threshold of the configuration
+type chatFormatter struct {
+	cfg       ChatConfig
+	dashboard *template.Template
+}
+
+func newChatFormatter(cfg ChatConfig) (*chatFormatter, error) {
+	var dashboard *template.Template
+	if cfg.DashboardURL != "" {
+		var err error
+		dashboard, err = template.New("dashboard").Funcs(templateFuncs).Parse(cfg.DashboardURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dashboard URL template: %w", err)
+		}
+	}
+	return &chatFormatter{
+		cfg:       cfg,
+		dashboard: dashboard,
+	}, nil
+}
+
+// send posts a digest when the number of alerts exceeds the digest threshold, a message per alert otherwise
+func (f *chatFormatter) send(alerts []Alert, post func(msg chatMessage) error) error {
+	if f.cfg.DigestThreshold > 0 && len(alerts) > f.cfg.DigestThreshold {
+		return post(f.digest(alerts))
+	}
+	for _, alert := range alerts {
+		msg, err := f.alert(alert)
+		if err != nil {
+			return err
+		}
+		if err := post(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alert returns the message about the alert
+func (f *chatFormatter) alert(alert Alert) (chatMessage, error) {
+	msg := chatMessage{
+		Level: alert.Level,
+		Title: fmt.Sprintf("%s/%s: %s", alert.ObjectRef.Namespace, alert.ObjectRef.Name, alert.Message),
+		Facts: []chatFact{
+			{Name: "Namespace", Value: alert.ObjectRef.Namespace},
+			{Name: "Name", Value: alert.ObjectRef.Name},
+			{Name: "Expiry", Value: expiry(alert)},
+			{Name: "Time remaining", Value: remaining(alert)},
+		},
+	}
+	if f.dashboard != nil {
+		var url bytes.Buffer
+		if err := f.dashboard.Execute(&url, alert); err != nil {
+			return chatMessage{}, fmt.Errorf("failed to render dashboard URL: %w", err)
+		}
+		msg.URL = url.String()
+	}
+	return msg, nil
+}
+
+// digest returns the message summarizing the alerts
+func (f *chatFormatter) digest(alerts []Alert) chatMessage {
+	var (
+		msg    chatMessage
+		counts = map[Level]int{}
+	)
+	for i, alert := range alerts {
+		if alert.Level > msg.Level {
+			msg.Level = alert.Level
+		}
+		counts[alert.Level]++
+		if i < maxDigestLines {
+			msg.Lines = append(msg.Lines, chatLine{
+				Level: alert.Level,
+				Text:  fmt.Sprintf("%s/%s, %s", alert.ObjectRef.Namespace, alert.ObjectRef.Name, remaining(alert)),
+			})
+		}
+	}
+	if len(alerts) > maxDigestLines {
+		msg.More = len(alerts) - maxDigestLines
+	}
+	msg.Title = fmt.Sprintf("%d certificates need attention: %d expired, %d about to expire",
+		len(alerts), counts[Error], counts[Warn])
+	return msg
+}
+
+// postChatMessage posts the message in JSON to the incoming webhook of the chat service
+func postChatMessage(client *httpClient, url, service string, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %w", service, err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", service, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := client.do(req); err != nil {
+		return fmt.Errorf("failed to post %s message: %w", service, err)
+	}
+	return nil
+}
+
+// markdownLines renders the lines of a digest as a markdown list
+func markdownLines(msg chatMessage) string {
+	lines := make([]string, 0, len(msg.Lines)+1)
+	for _, line := range msg.Lines {
+		lines = append(lines, fmt.Sprintf("- **%s** %s", line.Level, line.Text))
+	}
+	if msg.More > 0 {
+		lines = append(lines, fmt.Sprintf("- … and %d more", msg.More))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// levelColor returns the color of the alert level
+func levelColor(level Level) string {
+	switch level {
+	case Info:
+		return "#2eb886"
+	case Warn:
+		return "#daa038"
+	case Error:
+		return "#a30200"
+	default:
+		return "#808080"
+	}
+}
+
+// expiry returns the expiration date of the certificate
+func expiry(alert Alert) string {
+	if alert.Expiration == 0 {
+		return "unknown"
+	}
+	return time.Unix(0, alert.Expiration).UTC().Format(time.RFC3339)
+}
+
+// remaining returns a human readable duration until the expiration of the certificate when the alert was created
+func remaining(alert Alert) string {
+	if alert.Expiration == 0 {
+		return "unknown"
+	}
+	d := time.Duration(alert.Expiration - alert.When).Round(time.Second)
+	if d <= 0 {
+		return fmt.Sprintf("expired %s ago", -d)
+	}
+	return fmt.Sprintf("expires in %s", d)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Chat notifiers", func() {
+	var (
+		server   *httptest.Server
+		messages []json.RawMessage
+
+		cfg alert.ChatConfig
+		err error
+
+		now     = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		expired = alert.Alert{
+			Level:      alert.Error,
+			Message:    "certificate expired",
+			ObjectRef:  alert.ObjectRef{Name: "cert1", Namespace: "team-a"},
+			When:       now.UnixNano(),
+			Expiration: now.Add(-2 * time.Hour).UnixNano(),
+		}
+		expiring = alert.Alert{
+			Level:      alert.Warn,
+			Message:    "certificate is about to expire",
+			ObjectRef:  alert.ObjectRef{Name: "cert2", Namespace: "team-b"},
+			When:       now.UnixNano(),
+			Expiration: now.Add(90 * time.Minute).UnixNano(),
+		}
+	)
+
+	BeforeEach(func() {
+		messages = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			var msg json.RawMessage
+			Expect(json.NewDecoder(r.Body).Decode(&msg)).Should(Succeed())
+			messages = append(messages, msg)
+		}))
+		cfg = alert.ChatConfig{
+			WebhookURL:   server.URL,
+			DashboardURL: "https://pinot.example.com/certs?ns={{ .ObjectRef.Namespace | urlquery }}",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Teams", func() {
+		It("should post an Adaptive Card", func() {
+			notifier, err := alert.NewTeamsNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(expired)).Should(Succeed())
+			Expect(messages).Should(HaveLen(1))
+			Expect([]byte(messages[0])).Should(MatchJSON(`{
+				"type": "message",
+				"attachments": [{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content": {
+						"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+						"type": "AdaptiveCard",
+						"version": "1.4",
+						"body": [
+							{"type": "TextBlock", "text": "ERROR team-a/cert1: certificate expired", "weight": "Bolder", "size": "Medium", "color": "Attention", "wrap": true},
+							{"type": "FactSet", "facts": [
+								{"title": "Namespace", "value": "team-a"},
+								{"title": "Name", "value": "cert1"},
+								{"title": "Expiry", "value": "2021-12-31T22:00:00Z"},
+								{"title": "Time remaining", "value": "expired 2h0m0s ago"}
+							]}
+						],
+						"actions": [{"type": "Action.OpenUrl", "title": "Open dashboard", "url": "https://pinot.example.com/certs?ns=team-a"}],
+						"msteams": {"width": "Full"}
+					}
+				}]
+			}`))
+		})
+
+		It("should post a digest above the threshold", func() {
+			cfg.DigestThreshold = 1
+			notifier, err := alert.NewTeamsNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.SendBatch([]alert.Alert{expired, expiring})).Should(Succeed())
+			Expect(messages).Should(HaveLen(1))
+			var msg struct {
+				Attachments []struct {
+					Content struct {
+						Body []map[string]interface{} `json:"body"`
+					} `json:"content"`
+				} `json:"attachments"`
+			}
+			Expect(json.Unmarshal(messages[0], &msg)).Should(Succeed())
+			body := msg.Attachments[0].Content.Body
+			Expect(body[0]).Should(HaveKeyWithValue("text", "2 certificates need attention: 1 expired, 1 about to expire"))
+			Expect(body[0]).Should(HaveKeyWithValue("color", "Attention"))
+			Expect(body[1]).Should(HaveKeyWithValue("text",
+				"- **ERROR** team-a/cert1, expired 2h0m0s ago\n- **WARN** team-b/cert2, expires in 1h30m0s"))
+		})
+	})
+
+	Describe("Mattermost", func() {
+		It("should post an attachment with the certificate details", func() {
+			notifier, err := alert.NewMattermostNotifier(alert.MattermostConfig{
+				ChatConfig: cfg,
+				Channel:    "alerts",
+				Username:   "cert-monitor",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(expiring)).Should(Succeed())
+			Expect(messages).Should(HaveLen(1))
+			Expect([]byte(messages[0])).Should(MatchJSON(`{
+				"channel": "alerts",
+				"username": "cert-monitor",
+				"text": "WARN team-b/cert2: certificate is about to expire",
+				"attachments": [{
+					"fallback": "WARN team-b/cert2: certificate is about to expire",
+					"color": "#daa038",
+					"title": "WARN team-b/cert2: certificate is about to expire",
+					"title_link": "https://pinot.example.com/certs?ns=team-b",
+					"fields": [
+						{"title": "Namespace", "value": "team-b", "short": true},
+						{"title": "Name", "value": "cert2", "short": true},
+						{"title": "Expiry", "value": "2022-01-01T01:30:00Z", "short": true},
+						{"title": "Time remaining", "value": "expires in 1h30m0s", "short": true}
+					]
+				}]
+			}`))
+		})
+	})
+
+	Describe("Google Chat", func() {
+		It("should post a text message", func() {
+			cfg.DashboardURL = ""
+			notifier, err := alert.NewGoogleChatNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.SendBatch([]alert.Alert{expired})).Should(Succeed())
+			Expect(messages).Should(HaveLen(1))
+			Expect([]byte(messages[0])).Should(MatchJSON(`{
+				"text": "*ERROR* team-a/cert1: certificate expired\nNamespace: team-a\nName: cert1\nExpiry: 2021-12-31T22:00:00Z\nTime remaining: expired 2h0m0s ago"
+			}`))
+		})
+
+		It("should return an error when the dashboard URL template is invalid", func() {
+			cfg.DashboardURL = "{{ .Unknown"
+			_, err = alert.NewGoogleChatNotifier(cfg)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,110 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// CloudEventsStructured wraps the alert in a CloudEvents JSON envelope
+	CloudEventsStructured = "structured"
+	// CloudEventsBinary keeps the encoded alert as data and carries the CloudEvents attributes in the message metadata
+	CloudEventsBinary = "binary"
+
+	// CloudEventsSpecVersion is the version of the CloudEvents specification
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventType is the type of the CloudEvents emitted for the alerts
+	CloudEventType = "io.cert-monitor.certificate.expiring"
+	// CloudEventsContentType is the content type of the structured CloudEvents
+	CloudEventsContentType = "application/cloudevents+json"
+)
+
+// CloudEvent contains the attributes of the CloudEvent wrapping an alert
+type CloudEvent struct {
+	// ID identifies the event, it is derived from the alert so the same alert always has the same ID
+	ID string
+	// Source identifies where the event comes from, the cluster and the host of the monitor
+	Source string
+	// Subject is the certificate concerned by the alert
+	Subject string
+	// Time is when the alert has been created
+	Time time.Time
+	// DataContentType is the content type of the encoded alert
+	DataContentType string
+}
+
+// NewCloudEvent returns the CloudEvent attributes of the alert emitted from the cluster
+func NewCloudEvent(cluster string, alert Alert, dataContentType string) CloudEvent {
+	source := "/" + alert.Source
+	if cluster != "" {
+		source = "/" + cluster + source
+	}
+	subject := alert.ObjectRef.Namespace + "/" + alert.ObjectRef.Name
+	hash := sha256.New()
+	for _, s := range []string{source, subject, alert.Level.String(), strconv.FormatInt(alert.When, 10)} {
+		hash.Write([]byte(s))
+		hash.Write([]byte{0})
+	}
+	return CloudEvent{
+		ID:              hex.EncodeToString(hash.Sum(nil)[:16]),
+		Source:          source,
+		Subject:         subject,
+		Time:            time.Unix(0, alert.When).UTC(),
+		DataContentType: dataContentType,
+	}
+}
+
+// Attributes returns the context attributes of the event
+func (e CloudEvent) Attributes() map[string]string {
+	return map[string]string{
+		"specversion":     CloudEventsSpecVersion,
+		"id":              e.ID,
+		"source":          e.Source,
+		"type":            CloudEventType,
+		"subject":         e.Subject,
+		"time":            e.Time.Format(time.RFC3339Nano),
+		"datacontenttype": e.DataContentType,
+	}
+}
+
+// Structured returns the event in the JSON format with the data, the data is embedded as JSON when its content type
+// is JSON, base64 encoded otherwise
+func (e CloudEvent) Structured(data []byte) ([]byte, error) {
+	event := make(map[string]interface{}, 8)
+	for k, v := range e.Attributes() {
+		event[k] = v
+	}
+	if e.DataContentType == "application/json" {
+		event["data"] = json.RawMessage(data)
+	} else {
+		event["data_base64"] = data
+	}
+	out, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cloud event: %w", err)
+	}
+	return out, nil
+}
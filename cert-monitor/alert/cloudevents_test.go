@@ -0,0 +1,94 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CloudEvent", func() {
+	var (
+		a = alert.Alert{
+			Level:     alert.Error,
+			Message:   "certificate expired",
+			ObjectRef: alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:    "host",
+			When:      time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano(),
+		}
+	)
+
+	Describe("NewCloudEvent", func() {
+		It("should derive the attributes from the alert", func() {
+			event := alert.NewCloudEvent("prod", a, "application/json")
+			Expect(event.Source).Should(Equal("/prod/host"))
+			Expect(event.Subject).Should(Equal("ns/cert"))
+			Expect(event.Time).Should(Equal(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)))
+			Expect(event.ID).Should(HaveLen(32))
+		})
+
+		It("should use the host as source when cluster is not set", func() {
+			Expect(alert.NewCloudEvent("", a, "application/json").Source).Should(Equal("/host"))
+		})
+
+		It("should have a deterministic id", func() {
+			Expect(alert.NewCloudEvent("prod", a, "application/json").ID).
+				Should(Equal(alert.NewCloudEvent("prod", a, "application/json").ID))
+			other := a
+			other.Level = alert.Warn
+			Expect(alert.NewCloudEvent("prod", other, "application/json").ID).
+				ShouldNot(Equal(alert.NewCloudEvent("prod", a, "application/json").ID))
+		})
+	})
+
+	Describe("Structured", func() {
+		When("data is JSON", func() {
+			It("should embed the data", func() {
+				event := alert.NewCloudEvent("prod", a, "application/json")
+				data, err := event.Structured([]byte(`{"level":"ERROR"}`))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(data).Should(MatchJSON(`{
+					"specversion": "1.0",
+					"id": "` + event.ID + `",
+					"source": "/prod/host",
+					"type": "io.cert-monitor.certificate.expiring",
+					"subject": "ns/cert",
+					"time": "2022-01-02T03:04:05Z",
+					"datacontenttype": "application/json",
+					"data": {"level":"ERROR"}
+				}`))
+			})
+		})
+
+		When("data is binary", func() {
+			It("should embed the base64 encoded data", func() {
+				event := alert.NewCloudEvent("prod", a, "application/x-protobuf")
+				data, err := event.Structured([]byte{1, 2, 3})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(data).Should(ContainSubstring(`"data_base64":"AQID"`))
+				Expect(data).ShouldNot(ContainSubstring(`"data":`))
+			})
+		})
+	})
+})
@@ -19,7 +19,288 @@
 
 package alert
 
+import "time"
+
+// KafkaConfig contains the configuration of the kafka notifier
 type KafkaConfig struct {
+	// Topic is the kafka topic where the alerts are produced
 	Topic string `yaml:"topic"`
+	// Brokers is the list of kafka brokers to bootstrap the producer
 	Brokers []string `yaml:"brokers"`
-}
\ No newline at end of file
+	// Cluster is the name of the k8s cluster where the certificates are monitored
+	Cluster string `yaml:"cluster"`
+	// KeyIncludesCluster prefixes the message key with the cluster name, so alerts for certificates with the same
+	// name and namespace in different clusters do not share the same key
+	KeyIncludesCluster bool `yaml:"key_includes_cluster"`
+	// Async enables the asynchronous producer, the alerts are delivered in background
+	Async bool `yaml:"async"`
+	// MaxInFlight defines the maximum number of alerts waiting to be delivered by the asynchronous producer, sending an
+	// alert blocks when it is reached. DefaultMaxInFlight is used when not set.
+	MaxInFlight int `yaml:"max_in_flight"`
+	// Encoding defines how the alerts are encoded in the messages, one of json, avro or protobuf. JSON is used when not
+	// set.
+	Encoding string `yaml:"encoding"`
+	// SchemaRegistry contains the configuration of the schema registry, it is required by the avro encoding
+	SchemaRegistry SchemaRegistryConfig `yaml:"schema_registry"`
+	// CloudEvents defines the CloudEvents mode used to wrap the alerts, structured or binary. The alerts are not
+	// wrapped when not set.
+	CloudEvents string `yaml:"cloud_events"`
+	// Format defines the record of the alerts encoded in JSON, the alert is marshaled as is when not set
+	Format WireFormatConfig `yaml:"format"`
+}
+
+// WireFormatConfig defines the JSON record of the alerts, so it matches the schema of the table ingesting them
+type WireFormatConfig struct {
+	// Flatten flattens the fields of the nested objects with their path joined by dots, e.g. objectRef.name
+	Flatten bool `yaml:"flatten"`
+	// Rename maps the paths of the fields to new paths, e.g. objectRef.name: certificate. When the record is not
+	// flattened, the dots of the new paths nest the fields.
+	Rename map[string]string `yaml:"rename"`
+	// Fields contains static fields added to each record, e.g. cluster or environment
+	Fields map[string]string `yaml:"fields"`
+}
+
+// IsZero returns true when no format is configured
+func (c WireFormatConfig) IsZero() bool {
+	return !c.Flatten && len(c.Rename) == 0 && len(c.Fields) == 0
+}
+
+// SchemaRegistryConfig contains the configuration to reach a Confluent Schema Registry
+type SchemaRegistryConfig struct {
+	// URL is the base URL of the schema registry REST API
+	URL string `yaml:"url"`
+	// Subject is the subject of the alert schema, it defaults to <topic>-value
+	Subject string `yaml:"subject"`
+	// AutoRegister registers the alert schema when it is not found for the subject
+	AutoRegister bool `yaml:"auto_register"`
+	// Timeout defines the timeout of the calls to the schema registry
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// NotifiersConfig contains the configuration of the notifiers besides kafka, a notifier is enabled when its section is
+// set
+type NotifiersConfig struct {
+	// Webhook contains the configuration of the webhook notifier
+	Webhook *WebhookConfig `yaml:"webhook"`
+	// Slack contains the configuration of the slack notifier
+	Slack *SlackConfig `yaml:"slack"`
+	// Teams contains the configuration of the Microsoft Teams notifier
+	Teams *ChatConfig `yaml:"teams"`
+	// Mattermost contains the configuration of the Mattermost notifier
+	Mattermost *MattermostConfig `yaml:"mattermost"`
+	// GoogleChat contains the configuration of the Google Chat notifier
+	GoogleChat *ChatConfig `yaml:"google_chat"`
+	// PagerDuty contains the configuration of the PagerDuty notifier
+	PagerDuty *PagerDutyConfig `yaml:"pagerduty"`
+	// Opsgenie contains the configuration of the Opsgenie notifier
+	Opsgenie *OpsgenieConfig `yaml:"opsgenie"`
+	// Alertmanager contains the configuration of the Alertmanager notifier
+	Alertmanager *AlertmanagerConfig `yaml:"alertmanager"`
+	// KubernetesEvents contains the configuration of the notifier recording k8s events on the certificates
+	KubernetesEvents *KubernetesEventsConfig `yaml:"kubernetes_events"`
+	// Email contains the configuration of the email notifier
+	Email *EmailConfig `yaml:"email"`
+	// Syslog contains the configuration of the syslog notifier
+	Syslog *SyslogConfig `yaml:"syslog"`
+	// JSONLines contains the configuration of the JSON Lines notifier
+	JSONLines *JSONLinesConfig `yaml:"json_lines"`
+	// Pinot contains the configuration of the notifier ingesting the alerts directly in Pinot
+	Pinot *PinotConfig `yaml:"pinot"`
+}
+
+// WebhookConfig contains the configuration of the webhook notifier
+type WebhookConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	// URL is the URL called for each alert
+	URL string `yaml:"url"`
+	// Method is the HTTP method of the request, POST is used when not set
+	Method string `yaml:"method"`
+	// Headers contains the headers added to the request
+	Headers map[string]string `yaml:"headers"`
+	// Body is the Go template rendering the body of the request from the alert, the alert is rendered in JSON when not
+	// set
+	Body string `yaml:"body"`
+}
+
+// ChatConfig contains the configuration common to the chat notifiers posting to an incoming webhook
+type ChatConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	// WebhookURL is the URL of the incoming webhook
+	WebhookURL string `yaml:"webhook_url"`
+	// DashboardURL is the Go template rendering the URL of the dashboard linked in the messages from the alert
+	DashboardURL string `yaml:"dashboard_url"`
+	// DigestThreshold defines the number of alerts for a channel above which the alerts of a run are aggregated in a
+	// single digest message. The alerts are never aggregated when not set.
+	DigestThreshold int `yaml:"digest_threshold"`
+}
+
+// SlackConfig contains the configuration of the slack notifier
+type SlackConfig struct {
+	ChatConfig `yaml:",inline"`
+	// Channel overrides the default channel of the incoming webhook
+	Channel string `yaml:"channel"`
+	// Routes overrides the channel for the alerts of some namespaces, the first matching route is used
+	Routes []SlackRoute `yaml:"routes"`
+}
+
+// MattermostConfig contains the configuration of the Mattermost notifier
+type MattermostConfig struct {
+	ChatConfig `yaml:",inline"`
+	// Channel overrides the default channel of the incoming webhook
+	Channel string `yaml:"channel"`
+	// Username overrides the default username of the incoming webhook
+	Username string `yaml:"username"`
+}
+
+// SlackRoute routes the alerts of the matching namespaces to a channel
+type SlackRoute struct {
+	// Namespace is the pattern matching the namespace of the alerts, as defined by path.Match
+	Namespace string `yaml:"namespace"`
+	// Channel is the channel where the alerts are posted
+	Channel string `yaml:"channel"`
+}
+
+// PagerDutyConfig contains the configuration of the PagerDuty notifier
+type PagerDutyConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	// URL is the URL of the Events API v2, DefaultPagerDutyURL is used when not set
+	URL string `yaml:"url"`
+	// RoutingKeyFile is the path of the file containing the routing key of the PagerDuty service
+	RoutingKeyFile string `yaml:"routing_key_file"`
+	// TriggerLevel is the minimum level of the alerts triggering an incident, ERROR is used when not set
+	TriggerLevel Level `yaml:"trigger_level"`
+	// Cluster is the name of the k8s cluster, it is part of the deduplication key of the incidents
+	Cluster string `yaml:"cluster"`
+	// APIURL is the URL of the REST API, DefaultPagerDutyAPIURL is used when not set
+	APIURL string `yaml:"api_url"`
+	// APITokenFile is the path of the file containing a REST API token with read access, it is used to list the open
+	// incidents of the service so they are resolved. The bearer token and basic auth are not sent to the REST API.
+	APITokenFile string `yaml:"api_token_file"`
+	// ServiceID is the ID of the PagerDuty service of the routing key, its open incidents are listed
+	ServiceID string `yaml:"service_id"`
+}
+
+// OpsgenieConfig contains the configuration of the Opsgenie notifier
+type OpsgenieConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	// URL is the base URL of the Opsgenie API, DefaultOpsgenieURL is used when not set
+	URL string `yaml:"url"`
+	// APIKeyFile is the path of the file containing the key of the Opsgenie API integration, it needs the read access
+	// to list the open alerts
+	APIKeyFile string `yaml:"api_key_file"`
+	// Responders are the teams, users, escalations or schedules notified about the alerts
+	Responders []OpsgenieResponder `yaml:"responders"`
+	// Tags are the tags added to the alerts besides the cert-monitor tag
+	Tags []string `yaml:"tags"`
+	// Cluster is the name of the k8s cluster, it is part of the alias of the alerts
+	Cluster string `yaml:"cluster"`
+}
+
+// OpsgenieResponder is a responder of the Opsgenie alerts, identified by its name or its ID
+type OpsgenieResponder struct {
+	// Type is the type of the responder: team, user, escalation or schedule
+	Type string `yaml:"type"`
+	// Name is the name of the responder, the username for a user
+	Name string `yaml:"name"`
+	// ID is the ID of the responder
+	ID string `yaml:"id"`
+}
+
+// AlertmanagerConfig contains the configuration of the Alertmanager notifier
+type AlertmanagerConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	// URL is the base URL of Alertmanager
+	URL string `yaml:"url"`
+	// Cluster is the name of the k8s cluster, it is added as cluster label when set
+	Cluster string `yaml:"cluster"`
+	// GeneratorURL is the URL linked from the alerts
+	GeneratorURL string `yaml:"generator_url"`
+	// ResolveTimeout defines how long an alert fires when it is not sent again, the resolve_timeout of Alertmanager is
+	// used when not set
+	ResolveTimeout time.Duration `yaml:"resolve_timeout"`
+}
+
+// KubernetesEventsConfig contains the configuration of the notifier recording k8s events on the certificates
+type KubernetesEventsConfig struct {
+	// Component is the component reporting the events, DefaultEventsComponent when not set
+	Component string `yaml:"component"`
+	// Timeout is the timeout of the requests writing the events and of the wait for the next write when the notifier
+	// is closed, DefaultEventsTimeout when not set
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// EmailConfig contains the configuration of the email notifier
+type EmailConfig struct {
+	// Address is the host:port of the SMTP server
+	Address string `yaml:"address"`
+	// Username is the user name for the PLAIN authentication, no authentication is done when not set
+	Username string `yaml:"username"`
+	// PasswordFile is the path of the file containing the password
+	PasswordFile string `yaml:"password_file"`
+	// StartTLS requires the server to support STARTTLS, it is used whenever supported otherwise
+	StartTLS bool `yaml:"starttls"`
+	// TLS contains the TLS configuration used by STARTTLS
+	TLS *TLSConfig `yaml:"tls"`
+	// Timeout is the timeout of the SMTP session, DefaultEmailTimeout when not set
+	Timeout time.Duration `yaml:"timeout"`
+	// From is the sender address
+	From string `yaml:"from"`
+	// OwnerAnnotation is the annotation of the certificates listing the addresses of their owners,
+	// DefaultOwnerAnnotation when not set
+	OwnerAnnotation string `yaml:"owner_annotation"`
+	// Namespaces maps the namespaces to the addresses notified when the certificate has no owner annotation
+	Namespaces map[string][]string `yaml:"namespaces"`
+	// DefaultRecipients are the addresses notified when no other recipient is found
+	DefaultRecipients []string `yaml:"default_recipients"`
+	// Digest groups the alerts of a run in a single email per recipient
+	Digest bool `yaml:"digest"`
+}
+
+// SyslogConfig contains the configuration of the syslog notifier
+type SyslogConfig struct {
+	// Network is the network of the syslog server: udp, tcp, tls, unix, unixgram or journald
+	Network string `yaml:"network"`
+	// Address is the address of the syslog server, the path of the socket for unix networks. DefaultJournaldSocket is
+	// used for journald when not set.
+	Address string `yaml:"address"`
+	// TLS contains the TLS configuration of the tls network
+	TLS *TLSConfig `yaml:"tls"`
+	// Timeout is the timeout of the connection and the writes, DefaultSyslogTimeout when not set
+	Timeout time.Duration `yaml:"timeout"`
+	// Facility is the name of the syslog facility, daemon when not set
+	Facility string `yaml:"facility"`
+	// AppName is the app name of the messages, DefaultSyslogAppName when not set
+	AppName string `yaml:"app_name"`
+	// SDID is the ID of the structured data element, DefaultSyslogSDID when not set
+	SDID string `yaml:"sd_id"`
+	// Cluster is the name of the k8s cluster, it is added to the structured data when set
+	Cluster string `yaml:"cluster"`
+}
+
+// JSONLinesConfig contains the configuration of the JSON Lines notifier
+type JSONLinesConfig struct {
+	// Path is the path of the file, the alerts are written to the standard output when not set or set to -
+	Path string `yaml:"path"`
+	// MaxSize is the size in megabytes above which the file is rotated, the file is not rotated on its size when not
+	// set
+	MaxSize int `yaml:"max_size"`
+	// MaxAge is the duration after which the file is rotated, the file is not rotated on its age when not set
+	MaxAge time.Duration `yaml:"max_age"`
+	// MaxBackups is the number of rotated files kept, all the rotated files are kept when not set
+	MaxBackups int `yaml:"max_backups"`
+	// Compress compresses the rotated files with gzip
+	Compress bool `yaml:"compress"`
+}
+
+// PinotConfig contains the configuration of the notifier ingesting the alerts directly in Pinot
+type PinotConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	// ControllerURL is the base URL of the Pinot controller
+	ControllerURL string `yaml:"controller_url"`
+	// Table is the name of the OFFLINE table with its type, DefaultPinotTable when not set
+	Table string `yaml:"table"`
+	// BatchSize is the number of buffered alerts triggering an ingestion, DefaultPinotBatchSize when not set
+	BatchSize int `yaml:"batch_size"`
+	// FlushInterval is the maximum duration an alert is buffered, the alerts are only ingested on flush when not set
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
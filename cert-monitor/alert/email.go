@@ -0,0 +1,271 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// DefaultEmailTimeout is the timeout of the SMTP session when none is configured
+const DefaultEmailTimeout = 30 * time.Second
+
+var (
+	emailTextTemplate = template.Must(template.New("text").Funcs(emailFuncs).Parse(
+		`{{ range . }}{{ .Level }} {{ .ObjectRef.Namespace }}/{{ .ObjectRef.Name }}: {{ .Message }}, {{ remaining . }} ({{ expiry . }})
+{{ end }}`))
+
+	emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(emailFuncs).Parse(`<html>
+<body>
+<table>
+<tr><th>Level</th><th>Namespace</th><th>Certificate</th><th>Message</th><th>Expiry</th></tr>
+{{- range . }}
+<tr><td style="color: {{ levelColor .Level }}">{{ .Level }}</td><td>{{ .ObjectRef.Namespace }}</td><td>{{ .ObjectRef.Name }}</td><td>{{ .Message }}</td><td>{{ expiry . }} ({{ remaining . }})</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+	emailFuncs = map[string]interface{}{
+		"expiry":     expiry,
+		"remaining":  remaining,
+		"levelColor": levelColor,
+	}
+)
+
+// NewEmailNotifier returns a BatchNotifier sending HTML and plaintext emails with SMTP to the recipients returned by
+// the resolver. In digest mode, the alerts of a batch are grouped in a single email per recipient.
+func NewEmailNotifier(cfg EmailConfig, resolver RecipientResolver) (BatchNotifier, error) {
+	host, _, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SMTP address: %w", err)
+	}
+	var password string
+	if cfg.PasswordFile != "" {
+		data, err := ioutil.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SMTP password: %w", err)
+		}
+		password = strings.TrimSpace(string(data))
+	}
+	tlsConfig := &tls.Config{}
+	if cfg.TLS != nil {
+		tlsConfig, err = newTLSConfig(*cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tlsConfig.ServerName = host
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultEmailTimeout
+	}
+	return &emailNotifier{
+		cfg:       cfg,
+		host:      host,
+		password:  password,
+		tlsConfig: tlsConfig,
+		timeout:   timeout,
+		resolver:  resolver,
+	}, nil
+}
+
+type emailNotifier struct {
+	cfg       EmailConfig
+	host      string
+	password  string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	resolver  RecipientResolver
+}
+
+// Send implements Notifier contract
+func (e *emailNotifier) Send(alert Alert) error {
+	return e.SendBatch([]Alert{alert})
+}
+
+// SendBatch implements BatchNotifier contract, an email is sent per alert or per recipient in digest mode
+func (e *emailNotifier) SendBatch(alerts []Alert) error {
+	var (
+		err        error
+		recipients []string
+		byAddress  = map[string][]Alert{}
+	)
+	for _, alert := range alerts {
+		to, resolveErr := e.resolver.Recipients(alert)
+		if resolveErr != nil {
+			err = multierr.Append(err, resolveErr)
+			continue
+		}
+		if len(to) == 0 {
+			continue
+		}
+		if !e.cfg.Digest {
+			err = multierr.Append(err, e.mail(to, []Alert{alert}))
+			continue
+		}
+		for _, address := range to {
+			if _, ok := byAddress[address]; !ok {
+				recipients = append(recipients, address)
+			}
+			byAddress[address] = append(byAddress[address], alert)
+		}
+	}
+	for _, address := range recipients {
+		err = multierr.Append(err, e.mail([]string{address}, byAddress[address]))
+	}
+	return err
+}
+
+// Close implements Notifier contract
+func (e *emailNotifier) Close() error {
+	return nil
+}
+
+// mail sends an email about the alerts to the recipients
+func (e *emailNotifier) mail(to []string, alerts []Alert) error {
+	msg, err := e.message(to, alerts)
+	if err != nil {
+		return err
+	}
+	if err := e.deliver(to, msg); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", strings.Join(to, ", "), err)
+	}
+	return nil
+}
+
+// message returns the MIME message with the plaintext and HTML alternatives listing the alerts
+func (e *emailNotifier) message(to []string, alerts []Alert) ([]byte, error) {
+	subject := fmt.Sprintf("[cert-monitor] %d certificates need attention", len(alerts))
+	if len(alerts) == 1 {
+		alert := alerts[0]
+		subject = fmt.Sprintf("[cert-monitor] %s %s/%s: %s",
+			alert.Level, alert.ObjectRef.Namespace, alert.ObjectRef.Name, alert.Message)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	if err := writeEmailPart(parts, "text/plain", func(w io.Writer) error {
+		return emailTextTemplate.Execute(w, alerts)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render plaintext email: %w", err)
+	}
+	if err := writeEmailPart(parts, "text/html", func(w io.Writer) error {
+		return emailHTMLTemplate.Execute(w, alerts)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render HTML email: %w", err)
+	}
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render email: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// writeEmailPart writes a quoted-printable part of the content type rendered by the render function
+func writeEmailPart(parts *multipart.Writer, contentType string, render func(w io.Writer) error) error {
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	w := quotedprintable.NewWriter(part)
+	if err := render(w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// deliver sends the message with SMTP, STARTTLS is used when the server supports it and the authentication is done
+// when a username is configured
+func (e *emailNotifier) deliver(to []string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", e.cfg.Address, e.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(e.timeout)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(e.tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	} else if e.cfg.StartTLS {
+		return fmt.Errorf("SMTP server does not support STARTTLS")
+	}
+	if e.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.cfg.Username, e.password, e.host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	if err := client.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// smtpMessage is a message received by the fake SMTP server
+type smtpMessage struct {
+	from string
+	to   []string
+	auth string
+	data string
+}
+
+// fakeSMTPServer is a minimal SMTP server recording the messages it receives
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newFakeSMTPServer() *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ShouldNot(HaveOccurred())
+	server := &fakeSMTPServer{listener: listener}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(textproto.NewConn(conn))
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn *textproto.Conn) {
+	defer conn.Close()
+	var msg smtpMessage
+	_ = conn.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			_ = conn.PrintfLine("250-localhost")
+			_ = conn.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			msg.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+			_ = conn.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			_ = conn.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			_ = conn.PrintfLine("250 OK")
+		case "DATA":
+			_ = conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := ioutil.ReadAll(conn.DotReader())
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = smtpMessage{}
+			_ = conn.PrintfLine("250 OK")
+		case "QUIT":
+			_ = conn.PrintfLine("221 Bye")
+			return
+		default:
+			_ = conn.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) Messages() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) Close() {
+	_ = s.listener.Close()
+}
+
+// parseEmail returns the subject and the plaintext and HTML parts of the email
+func parseEmail(data string) (subject string, parts map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	Expect(err).ShouldNot(HaveOccurred())
+	subject, err = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	Expect(err).ShouldNot(HaveOccurred())
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(mediaType).Should(Equal("multipart/alternative"))
+	parts = map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		Expect(err).ShouldNot(HaveOccurred())
+		body, err := ioutil.ReadAll(part)
+		Expect(err).ShouldNot(HaveOccurred())
+		parts[contentType] = string(body)
+	}
+	return subject, parts
+}
+
+var _ = Describe("Email", func() {
+	var (
+		server *fakeSMTPServer
+		cfg    alert.EmailConfig
+		alerts []alert.Alert
+		err    error
+
+		now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		server = newFakeSMTPServer()
+		cfg = alert.EmailConfig{
+			Address: server.listener.Addr().String(),
+			From:    "cert-monitor@example.com",
+			Namespaces: map[string][]string{
+				"team-a": {"a@example.com", "ops@example.com"},
+				"team-b": {"b@example.com", "ops@example.com"},
+			},
+		}
+		alerts = []alert.Alert{
+			{
+				Level:      alert.Error,
+				Message:    "certificate expired",
+				ObjectRef:  alert.ObjectRef{Name: "cert1", Namespace: "team-a"},
+				When:       now.UnixNano(),
+				Expiration: now.Add(-time.Hour).UnixNano(),
+			},
+			{
+				Level:      alert.Warn,
+				Message:    "certificate is about to expire",
+				ObjectRef:  alert.ObjectRef{Name: "cert2", Namespace: "team-b"},
+				When:       now.UnixNano(),
+				Expiration: now.Add(48 * time.Hour).UnixNano(),
+			},
+			{
+				Level:     alert.Warn,
+				Message:   "certificate is about to expire",
+				ObjectRef: alert.ObjectRef{Name: "cert3", Namespace: "team-c"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		var notifier alert.BatchNotifier
+		notifier, err = alert.NewEmailNotifier(cfg, alert.NewRecipientResolver(cfg))
+		Expect(err).ShouldNot(HaveOccurred())
+		err = notifier.SendBatch(alerts)
+	})
+
+	When("digest is disabled", func() {
+		It("should send an email per alert to its recipients", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			messages := server.Messages()
+			Expect(messages).Should(HaveLen(2))
+
+			Expect(messages[0].from).Should(Equal("cert-monitor@example.com"))
+			Expect(messages[0].to).Should(Equal([]string{"a@example.com", "ops@example.com"}))
+			subject, parts := parseEmail(messages[0].data)
+			Expect(subject).Should(Equal("[cert-monitor] ERROR team-a/cert1: certificate expired"))
+			Expect(parts["text/plain"]).Should(Equal(
+				"ERROR team-a/cert1: certificate expired, expired 1h0m0s ago (2021-12-31T23:00:00Z)\n"))
+			Expect(parts["text/html"]).Should(ContainSubstring(
+				`<tr><td style="color: #a30200">ERROR</td><td>team-a</td><td>cert1</td><td>certificate expired</td>`))
+
+			Expect(messages[1].to).Should(Equal([]string{"b@example.com", "ops@example.com"}))
+		})
+	})
+
+	When("digest is enabled", func() {
+		BeforeEach(func() {
+			cfg.Digest = true
+			cfg.DefaultRecipients = []string{"admin@example.com"}
+		})
+		It("should send a single email per recipient", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			messages := server.Messages()
+			Expect(messages).Should(HaveLen(4))
+			byRecipient := map[string]string{}
+			for _, msg := range messages {
+				Expect(msg.to).Should(HaveLen(1))
+				byRecipient[msg.to[0]] = msg.data
+			}
+			Expect(byRecipient).Should(HaveKey("admin@example.com"))
+
+			subject, parts := parseEmail(byRecipient["ops@example.com"])
+			Expect(subject).Should(Equal("[cert-monitor] 2 certificates need attention"))
+			Expect(parts["text/plain"]).Should(Equal(
+				"ERROR team-a/cert1: certificate expired, expired 1h0m0s ago (2021-12-31T23:00:00Z)\n" +
+					"WARN team-b/cert2: certificate is about to expire, expires in 48h0m0s (2022-01-03T00:00:00Z)\n"))
+
+			subject, _ = parseEmail(byRecipient["a@example.com"])
+			Expect(subject).Should(Equal("[cert-monitor] ERROR team-a/cert1: certificate expired"))
+		})
+	})
+
+	When("authentication is configured", func() {
+		BeforeEach(func() {
+			passwordFile := writeTempFile("password", []byte("secret\n"))
+			cfg.Username = "user"
+			cfg.PasswordFile = passwordFile
+			alerts = alerts[:1]
+		})
+		It("should authenticate with the credentials", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			messages := server.Messages()
+			Expect(messages).Should(HaveLen(1))
+			Expect(messages[0].auth).Should(Equal(base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))))
+		})
+	})
+
+	When("STARTTLS is required but not supported by the server", func() {
+		BeforeEach(func() {
+			cfg.StartTLS = true
+			alerts = alerts[:1]
+		})
+		It("should return an error", func() {
+			Expect(err).Should(MatchError(
+				"failed to send email to a@example.com, ops@example.com: SMTP server does not support STARTTLS"))
+			Expect(server.Messages()).Should(BeEmpty())
+		})
+	})
+})
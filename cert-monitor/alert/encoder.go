@@ -0,0 +1,88 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// EncodingJSON encodes the alerts as JSON objects
+	EncodingJSON = "json"
+	// EncodingAvro encodes the alerts in Avro using the Confluent wire format
+	EncodingAvro = "avro"
+	// EncodingProtobuf encodes the alerts in Protobuf
+	EncodingProtobuf = "protobuf"
+)
+
+// Encoder encodes an alert as the value of a message
+type Encoder interface {
+	// Encode returns the encoded alert
+	Encode(alert Alert) ([]byte, error)
+	// ContentType returns the content type of the encoded alerts
+	ContentType() string
+}
+
+// NewEncoder returns the Encoder defined by the configuration, JSON is used when no encoding is set. The format only
+// applies to the JSON encoding.
+func NewEncoder(cfg KafkaConfig) (Encoder, error) {
+	if !cfg.Format.IsZero() && cfg.Encoding != "" && cfg.Encoding != EncodingJSON {
+		return nil, fmt.Errorf("format is not supported by the %s encoding", cfg.Encoding)
+	}
+	switch cfg.Encoding {
+	case "", EncodingJSON:
+		if !cfg.Format.IsZero() {
+			return NewFormattedJSONEncoder(cfg.Format)
+		}
+		return NewJSONEncoder(), nil
+	case EncodingAvro:
+		subject := cfg.SchemaRegistry.Subject
+		if subject == "" {
+			subject = cfg.Topic + "-value"
+		}
+		return NewAvroEncoder(NewSchemaRegistryClient(cfg.SchemaRegistry), subject, cfg.SchemaRegistry.AutoRegister), nil
+	case EncodingProtobuf:
+		return NewProtobufEncoder(), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", cfg.Encoding)
+	}
+}
+
+// NewJSONEncoder returns an Encoder that marshals the alerts in JSON
+func NewJSONEncoder() Encoder {
+	return jsonEncoder{}
+}
+
+type jsonEncoder struct{}
+
+// Encode implements Encoder contract
+func (jsonEncoder) Encode(alert Alert) ([]byte, error) {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal alert in JSON: %w", err)
+	}
+	return data, nil
+}
+
+// ContentType implements Encoder contract
+func (jsonEncoder) ContentType() string {
+	return "application/json"
+}
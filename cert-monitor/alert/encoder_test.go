@@ -0,0 +1,77 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encoder", func() {
+
+	Describe("NewEncoder", func() {
+		When("encoding is not set", func() {
+			It("should return a JSON encoder", func() {
+				encoder, err := alert.NewEncoder(alert.KafkaConfig{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(encoder.ContentType()).Should(Equal("application/json"))
+			})
+		})
+
+		When("encoding is avro", func() {
+			It("should return an Avro encoder", func() {
+				encoder, err := alert.NewEncoder(alert.KafkaConfig{Encoding: alert.EncodingAvro})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(encoder.ContentType()).Should(Equal("application/vnd.confluent.avro"))
+			})
+		})
+
+		When("encoding is protobuf", func() {
+			It("should return a Protobuf encoder", func() {
+				encoder, err := alert.NewEncoder(alert.KafkaConfig{Encoding: alert.EncodingProtobuf})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(encoder.ContentType()).Should(Equal("application/x-protobuf"))
+			})
+		})
+
+		When("encoding is unknown", func() {
+			It("should return an error", func() {
+				_, err := alert.NewEncoder(alert.KafkaConfig{Encoding: "xml"})
+				Expect(err).Should(MatchError(`unsupported encoding "xml"`))
+			})
+		})
+	})
+
+	Describe("JSON", func() {
+		It("should marshal the alert in JSON", func() {
+			data, err := alert.NewJSONEncoder().Encode(alert.Alert{
+				Level:     alert.Warn,
+				Message:   "cert is about to expire",
+				ObjectRef: alert.ObjectRef{Name: "cert", Namespace: "ns"},
+				Source:    "host-123",
+				When:      1234567890,
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(data).Should(MatchJSON(`{"level":"WARN","message":"cert is about to expire","objectRef":{"name":"cert","namespace":"ns"},"source":"host-123","when":1234567890}`))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import "context"
+
+// NewLevelFilterNotifier returns a Notifier that only forwards the alerts having at least the minimum level to the
+// notifier
+func NewLevelFilterNotifier(notifier Notifier, min Level) Notifier {
+	return &levelFilterNotifier{
+		notifier: notifier,
+		min:      min,
+	}
+}
+
+type levelFilterNotifier struct {
+	notifier Notifier
+	min      Level
+}
+
+// Send implements Notifier contract
+func (l *levelFilterNotifier) Send(alert Alert) error {
+	if alert.Level < l.min {
+		return nil
+	}
+	return l.notifier.Send(alert)
+}
+
+// SendBatch implements BatchNotifier contract
+func (l *levelFilterNotifier) SendBatch(alerts []Alert) error {
+	var filtered []Alert
+	for _, alert := range alerts {
+		if alert.Level >= l.min {
+			filtered = append(filtered, alert)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	if batchNotifier, ok := l.notifier.(BatchNotifier); ok {
+		return batchNotifier.SendBatch(filtered)
+	}
+	for _, alert := range filtered {
+		if err := l.notifier.Send(alert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush implements AsyncNotifier contract
+func (l *levelFilterNotifier) Flush(ctx context.Context) error {
+	if asyncNotifier, ok := l.notifier.(AsyncNotifier); ok {
+		return asyncNotifier.Flush(ctx)
+	}
+	return nil
+}
+
+// Close implements Notifier contract
+func (l *levelFilterNotifier) Close() error {
+	return l.notifier.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LevelFilterNotifier", func() {
+	var (
+		notifierMock *mocks.Notifier
+		notifier     alert.Notifier
+
+		valid    = alert.Alert{Level: alert.Info, ObjectRef: alert.ObjectRef{Name: "valid"}}
+		expiring = alert.Alert{Level: alert.Warn, ObjectRef: alert.ObjectRef{Name: "expiring"}}
+		expired  = alert.Alert{Level: alert.Error, ObjectRef: alert.ObjectRef{Name: "expired"}}
+	)
+
+	BeforeEach(func() {
+		notifierMock = &mocks.Notifier{}
+		notifier = alert.NewLevelFilterNotifier(notifierMock, alert.Warn)
+	})
+
+	AfterEach(func() {
+		notifierMock.AssertExpectations(GinkgoT())
+	})
+
+	Describe("Send", func() {
+		It("should drop the alerts below the minimum level", func() {
+			Expect(notifier.Send(valid)).Should(Succeed())
+		})
+
+		It("should forward the other alerts", func() {
+			notifierMock.On("Send", expiring).Return(nil).Once()
+			Expect(notifier.Send(expiring)).Should(Succeed())
+		})
+	})
+
+	Describe("SendBatch", func() {
+		It("should only forward the alerts with the minimum level", func() {
+			notifierMock.On("Send", expiring).Return(nil).Once()
+			notifierMock.On("Send", expired).Return(nil).Once()
+			Expect(notifier.(alert.BatchNotifier).SendBatch([]alert.Alert{valid, expiring, expired})).Should(Succeed())
+		})
+
+		It("should forward the batch to batch notifiers", func() {
+			batchNotifierMock := &mocks.BatchNotifier{}
+			batchNotifierMock.On("SendBatch", []alert.Alert{expiring, expired}).Return(nil).Once()
+			notifier = alert.NewLevelFilterNotifier(batchNotifierMock, alert.Warn)
+			Expect(notifier.(alert.BatchNotifier).SendBatch([]alert.Alert{valid, expiring, expired})).Should(Succeed())
+			batchNotifierMock.AssertExpectations(GinkgoT())
+		})
+	})
+})
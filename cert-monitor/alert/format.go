@@ -0,0 +1,121 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NewFormattedJSONEncoder returns an Encoder that marshals the alerts in JSON records defined by the format. The fields
+// are renamed and the static fields are added on the flattened alert, the record is nested back unless it is
+// flattened.
+func NewFormattedJSONEncoder(format WireFormatConfig) (Encoder, error) {
+	targets := make(map[string]string, len(format.Rename))
+	for from, to := range format.Rename {
+		if other, found := targets[to]; found {
+			return nil, fmt.Errorf("failed to create JSON encoder: %s and %s are both renamed to %s", other, from, to)
+		}
+		targets[to] = from
+	}
+	for field := range format.Fields {
+		if from, found := targets[field]; found {
+			return nil, fmt.Errorf("failed to create JSON encoder: static field %s conflicts with the rename of %s", field, from)
+		}
+	}
+	return formattedJSONEncoder{format: format}, nil
+}
+
+type formattedJSONEncoder struct {
+	format WireFormatConfig
+}
+
+// Encode implements Encoder contract
+func (e formattedJSONEncoder) Encode(alert Alert) ([]byte, error) {
+	record, err := e.record(alert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format alert: %w", err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal alert in JSON: %w", err)
+	}
+	return data, nil
+}
+
+// ContentType implements Encoder contract
+func (formattedJSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (e formattedJSONEncoder) record(alert Alert) (map[string]interface{}, error) {
+	fields, err := flattenAlert(alert)
+	if err != nil {
+		return nil, err
+	}
+	flat := make(map[string]interface{}, len(fields)+len(e.format.Fields))
+	for path, value := range fields {
+		if to, found := e.format.Rename[path]; found {
+			path = to
+		}
+		if _, found := flat[path]; found {
+			return nil, fmt.Errorf("field %s is set twice", path)
+		}
+		flat[path] = value
+	}
+	for path, value := range e.format.Fields {
+		if _, found := flat[path]; found {
+			return nil, fmt.Errorf("static field %s is already set by the alert", path)
+		}
+		flat[path] = value
+	}
+	if e.format.Flatten {
+		return flat, nil
+	}
+	return unflatten(flat)
+}
+
+// unflatten nests the fields whose path contains dots, e.g. objectRef.name becomes the name field of objectRef
+func unflatten(flat map[string]interface{}) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(flat))
+	for path, value := range flat {
+		keys := strings.Split(path, ".")
+		parent := record
+		for _, key := range keys[:len(keys)-1] {
+			switch child := parent[key].(type) {
+			case nil:
+				nested := map[string]interface{}{}
+				parent[key] = nested
+				parent = nested
+			case map[string]interface{}:
+				parent = child
+			default:
+				return nil, fmt.Errorf("field %s conflicts with field %s", path, key)
+			}
+		}
+		last := keys[len(keys)-1]
+		if _, found := parent[last]; found {
+			return nil, fmt.Errorf("field %s conflicts with a nested field", path)
+		}
+		parent[last] = value
+	}
+	return record, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FormattedJSONEncoder", func() {
+	var (
+		format alert.WireFormatConfig
+		a      alert.Alert
+		data   []byte
+		err    error
+	)
+
+	BeforeEach(func() {
+		format = alert.WireFormatConfig{}
+		a = alert.Alert{
+			Level:      alert.Warn,
+			Message:    "cert is about to expire",
+			ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:     "host-123",
+			When:       1640995200000000001,
+			Expiration: 1640998800000000001,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var encoder alert.Encoder
+		encoder, err = alert.NewEncoder(alert.KafkaConfig{Format: format})
+		if err != nil {
+			return
+		}
+		Expect(encoder.ContentType()).Should(Equal("application/json"))
+		data, err = encoder.Encode(a)
+	})
+
+	When("record is flattened", func() {
+		BeforeEach(func() {
+			format.Flatten = true
+		})
+
+		It("should join the paths of the nested fields with dots", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(data).Should(MatchJSON(`{
+				"level": "WARN",
+				"message": "cert is about to expire",
+				"objectRef.name": "cert",
+				"objectRef.namespace": "ns",
+				"source": "host-123",
+				"when": 1640995200000000001,
+				"expiration": 1640998800000000001
+			}`))
+		})
+	})
+
+	When("fields are renamed and static fields are added", func() {
+		BeforeEach(func() {
+			format.Rename = map[string]string{
+				"objectRef.name":      "certificate.name",
+				"objectRef.namespace": "certificate.namespace",
+				"when":                "timestamp",
+			}
+			format.Fields = map[string]string{"cluster": "prod", "env.name": "production"}
+		})
+
+		It("should nest the renamed fields", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(data).Should(MatchJSON(`{
+				"level": "WARN",
+				"message": "cert is about to expire",
+				"certificate": {"name": "cert", "namespace": "ns"},
+				"source": "host-123",
+				"timestamp": 1640995200000000001,
+				"expiration": 1640998800000000001,
+				"cluster": "prod",
+				"env": {"name": "production"}
+			}`))
+		})
+	})
+
+	When("static field is set by the alert", func() {
+		BeforeEach(func() {
+			format.Fields = map[string]string{"source": "static"}
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError("failed to format alert: static field source is already set by the alert"))
+		})
+	})
+
+	When("renamed field conflicts with a nested field", func() {
+		BeforeEach(func() {
+			format.Rename = map[string]string{"message": "objectRef"}
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError(ContainSubstring("conflicts")))
+		})
+	})
+
+	When("two fields are renamed to the same field", func() {
+		BeforeEach(func() {
+			format.Rename = map[string]string{"message": "text", "source": "text"}
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError(ContainSubstring("are both renamed to text")))
+		})
+	})
+
+	When("encoding is not JSON", func() {
+		It("should reject the format", func() {
+			_, err := alert.NewEncoder(alert.KafkaConfig{Encoding: alert.EncodingProtobuf, Format: alert.WireFormatConfig{Flatten: true}})
+			Expect(err).Should(MatchError("format is not supported by the protobuf encoding"))
+		})
+	})
+})
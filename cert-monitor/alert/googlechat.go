@@ -0,0 +1,100 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewGoogleChatNotifier returns a BatchNotifier that posts the alerts as text messages to a Google Chat incoming
+// webhook. The alerts of a batch are aggregated in a single digest message when their number exceeds the digest
+// threshold.
+func NewGoogleChatNotifier(cfg ChatConfig) (BatchNotifier, error) {
+	formatter, err := newChatFormatter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Chat client: %w", err)
+	}
+	return &googleChatNotifier{
+		cfg:       cfg,
+		formatter: formatter,
+		client:    client,
+	}, nil
+}
+
+type googleChatNotifier struct {
+	cfg       ChatConfig
+	formatter *chatFormatter
+	client    *httpClient
+}
+
+type googleChatMessage struct {
+	Text string `json:"text"`
+}
+
+// Send implements Notifier contract
+func (g *googleChatNotifier) Send(alert Alert) error {
+	msg, err := g.formatter.alert(alert)
+	if err != nil {
+		return err
+	}
+	return g.post(msg)
+}
+
+// SendBatch implements BatchNotifier contract
+func (g *googleChatNotifier) SendBatch(alerts []Alert) error {
+	return g.formatter.send(alerts, g.post)
+}
+
+// Close implements Notifier contract
+func (g *googleChatNotifier) Close() error {
+	return nil
+}
+
+// message renders the chat message with the Google Chat text formatting
+func (g *googleChatNotifier) message(msg chatMessage) googleChatMessage {
+	var lines []string
+	if msg.Digest() {
+		lines = append(lines, "*"+msg.Title+"*")
+		for _, line := range msg.Lines {
+			lines = append(lines, fmt.Sprintf("• *%s* %s", line.Level, line.Text))
+		}
+		if msg.More > 0 {
+			lines = append(lines, fmt.Sprintf("… and %d more", msg.More))
+		}
+	} else {
+		lines = append(lines, fmt.Sprintf("*%s* %s", msg.Level, msg.Title))
+		for _, fact := range msg.Facts {
+			lines = append(lines, fmt.Sprintf("%s: %s", fact.Name, fact.Value))
+		}
+	}
+	if msg.URL != "" {
+		lines = append(lines, fmt.Sprintf("<%s|Open dashboard>", msg.URL))
+	}
+	return googleChatMessage{Text: strings.Join(lines, "\n")}
+}
+
+func (g *googleChatNotifier) post(msg chatMessage) error {
+	return postChatMessage(g.client, g.cfg.WebhookURL, "Google Chat", g.message(msg))
+}
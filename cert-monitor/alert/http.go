@@ -0,0 +1,162 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxErrorBodySize is the maximum number of bytes of the response body reported in the errors
+const maxErrorBodySize = 512
+
+// HTTPClientConfig contains the configuration of the HTTP client used by the notifiers calling an HTTP API
+type HTTPClientConfig struct {
+	// Timeout defines the timeout of the requests
+	Timeout time.Duration `yaml:"timeout"`
+	// BearerTokenFile is the path of the file containing the token sent as bearer in the Authorization header
+	BearerTokenFile string `yaml:"bearer_token_file"`
+	// BasicAuth contains the credentials for the basic authentication
+	BasicAuth *BasicAuthConfig `yaml:"basic_auth"`
+	// TLS contains the TLS configuration, the client certificate is used for mTLS
+	TLS *TLSConfig `yaml:"tls"`
+}
+
+// BasicAuthConfig contains the credentials for the basic authentication
+type BasicAuthConfig struct {
+	// Username is the user name
+	Username string `yaml:"username"`
+	// Password is the password
+	Password string `yaml:"password"`
+}
+
+// TLSConfig contains the TLS configuration of an HTTP client
+type TLSConfig struct {
+	// CAFile is the path of the PEM encoded CA certificates used to verify the server, system CAs are used when not set
+	CAFile string `yaml:"ca_file"`
+	// CertFile is the path of the PEM encoded client certificate
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the path of the PEM encoded client private key
+	KeyFile string `yaml:"key_file"`
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+// httpClient sends requests with the configured authentication and turns non 2xx responses into errors
+type httpClient struct {
+	cfg    HTTPClientConfig
+	client *http.Client
+}
+
+func newHTTPClient(cfg HTTPClientConfig) (*httpClient, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLS != nil {
+		tlsConfig, err := newTLSConfig(*cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &httpClient{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
+func newTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		data, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no CA certificate found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// do sends the request and returns the body of the response, an error is returned when the status is not 2xx with the
+// beginning of the response body
+func (c *httpClient) do(req *http.Request) ([]byte, error) {
+	if err := c.authenticate(req); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+		msg := strings.TrimSpace(string(body))
+		if len(body) > maxErrorBodySize {
+			// the body is cut at the start of a character so the message stays valid UTF-8
+			cut := maxErrorBodySize
+			for cut > 0 && !utf8.RuneStart(body[cut]) {
+				cut--
+			}
+			msg = strings.TrimSpace(string(body[:cut])) + "..."
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+func (c *httpClient) authenticate(req *http.Request) error {
+	if c.cfg.BearerTokenFile != "" {
+		// the token is read for each request to follow the rotation of the mounted secret
+		token, err := ioutil.ReadFile(c.cfg.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	} else if c.cfg.BasicAuth != nil {
+		req.SetBasicAuth(c.cfg.BasicAuth.Username, c.cfg.BasicAuth.Password)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import "sync"
+
+// openIncidents lists once the keys of the incidents opened before the run, so the certificates are only resolved in
+// the incident management system when they were alerting
+type openIncidents struct {
+	list func() (map[string]struct{}, error)
+
+	once sync.Once
+	keys map[string]struct{}
+	err  error
+}
+
+// isOpen returns true when the incident of the key is open, the incidents are listed on the first call
+func (o *openIncidents) isOpen(key string) (bool, error) {
+	o.once.Do(func() {
+		o.keys, o.err = o.list()
+	})
+	if o.err != nil {
+		return false, o.err
+	}
+	_, ok := o.keys[key]
+	return ok, nil
+}
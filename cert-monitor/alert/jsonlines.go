@@ -0,0 +1,70 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// NewJSONLinesNotifier returns a Notifier writing each alert as a JSON line to the standard output when no path is
+// configured, to a file rotated on its size or its age otherwise
+func NewJSONLinesNotifier(cfg JSONLinesConfig) (Notifier, error) {
+	if cfg.Path == "" || cfg.Path == "-" {
+		return &jsonLinesNotifier{w: os.Stdout}, nil
+	}
+	file, err := newRotatingFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLinesNotifier{w: file, closer: file}, nil
+}
+
+type jsonLinesNotifier struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// Send implements Notifier contract
+func (j *jsonLinesNotifier) Send(alert Alert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+	line = append(line, '\n')
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write alert: %w", err)
+	}
+	return nil
+}
+
+// Close implements Notifier contract, the standard output is left open
+func (j *jsonLinesNotifier) Close() error {
+	if j.closer == nil {
+		return nil
+	}
+	return j.closer.Close()
+}
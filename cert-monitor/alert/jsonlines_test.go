@@ -0,0 +1,176 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// readLines returns the lines of the file, gunzipped when its name ends with .gz
+func readLines(path string) []string {
+	file, err := os.Open(path)
+	Expect(err).ShouldNot(HaveOccurred())
+	defer file.Close()
+	var scanner *bufio.Scanner
+	if strings.HasSuffix(path, ".gz") {
+		r, err := gzip.NewReader(file)
+		Expect(err).ShouldNot(HaveOccurred())
+		scanner = bufio.NewScanner(r)
+	} else {
+		scanner = bufio.NewScanner(file)
+	}
+	scanner.Buffer(nil, 2*1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	Expect(scanner.Err()).ShouldNot(HaveOccurred())
+	return lines
+}
+
+var _ = Describe("JSONLines", func() {
+	var (
+		dir string
+		cfg alert.JSONLinesConfig
+
+		alerts = []alert.Alert{
+			{Level: alert.Error, Message: "certificate expired", ObjectRef: alert.ObjectRef{Name: "cert1", Namespace: "ns"}},
+			{Level: alert.Warn, Message: "certificate is about to expire", ObjectRef: alert.ObjectRef{Name: "cert2", Namespace: "ns"}},
+			{Level: alert.Warn, Message: "certificate is about to expire", ObjectRef: alert.ObjectRef{Name: "cert3", Namespace: "ns"}},
+			{Level: alert.Error, Message: "certificate expired", ObjectRef: alert.ObjectRef{Name: "cert4", Namespace: "ns"}},
+		}
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir(tempDir, "jsonlines")
+		Expect(err).ShouldNot(HaveOccurred())
+		cfg = alert.JSONLinesConfig{Path: filepath.Join(dir, "alerts.jsonl")}
+	})
+
+	send := func(alerts ...alert.Alert) {
+		notifier, err := alert.NewJSONLinesNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+		for _, a := range alerts {
+			Expect(notifier.Send(a)).Should(Succeed())
+		}
+		Expect(notifier.Close()).Should(Succeed())
+	}
+
+	It("should write a JSON line per alert", func() {
+		send(alerts[:2]...)
+		send(alerts[2])
+		lines := readLines(cfg.Path)
+		Expect(lines).Should(HaveLen(3))
+		var a alert.Alert
+		Expect(json.Unmarshal([]byte(lines[1]), &a)).Should(Succeed())
+		Expect(a).Should(Equal(alerts[1]))
+	})
+
+	When("file exceeds its maximum age", func() {
+		BeforeEach(func() {
+			cfg.MaxAge = 1
+			cfg.MaxBackups = 2
+			cfg.Compress = true
+		})
+		It("should rotate, compress and prune the files", func() {
+			send(alerts...)
+			Expect(readLines(cfg.Path)).Should(HaveLen(1))
+			rotated, err := filepath.Glob(filepath.Join(dir, "alerts-*.jsonl.gz"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rotated).Should(HaveLen(2))
+			Expect(readLines(rotated[0])[0]).Should(ContainSubstring("cert2"))
+			Expect(readLines(rotated[1])[0]).Should(ContainSubstring("cert3"))
+			uncompressed, err := filepath.Glob(filepath.Join(dir, "alerts-*.jsonl"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(uncompressed).Should(BeEmpty())
+		})
+	})
+
+	When("existing file exceeds its maximum age", func() {
+		BeforeEach(func() {
+			cfg.MaxAge = time.Hour
+			cfg.MaxBackups = 1
+			Expect(ioutil.WriteFile(cfg.Path, []byte("{}\n"), 0644)).Should(Succeed())
+		})
+		It("should rotate the file modified before the maximum age", func() {
+			old := time.Now().Add(-2 * time.Hour)
+			Expect(os.Chtimes(cfg.Path, old, old)).Should(Succeed())
+			send(alerts[0])
+			Expect(readLines(cfg.Path)).Should(HaveLen(1))
+			rotated, err := filepath.Glob(filepath.Join(dir, "alerts-*.jsonl"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rotated).Should(HaveLen(1))
+		})
+		It("should count the age from the last rotation", func() {
+			lastRotation := time.Now().Add(-2 * time.Hour).UTC().Format("2006-01-02T15-04-05.000000000")
+			Expect(ioutil.WriteFile(filepath.Join(dir, "alerts-"+lastRotation+".jsonl"), nil, 0644)).Should(Succeed())
+			send(alerts[0])
+			Expect(readLines(cfg.Path)).Should(HaveLen(1))
+		})
+		It("should only prune the rotated files", func() {
+			old := time.Now().Add(-2 * time.Hour)
+			Expect(os.Chtimes(cfg.Path, old, old)).Should(Succeed())
+			unrelated := filepath.Join(dir, "alerts-archive.jsonl")
+			Expect(ioutil.WriteFile(unrelated, nil, 0644)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "alerts-2021-01-01T00-00-00.000000000.jsonl"), nil, 0644)).Should(Succeed())
+			send(alerts[0])
+			rotated, err := filepath.Glob(filepath.Join(dir, "alerts-*.jsonl"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rotated).Should(HaveLen(2))
+			Expect(rotated).Should(ContainElement(unrelated))
+		})
+	})
+
+	When("file exceeds its maximum size", func() {
+		BeforeEach(func() {
+			cfg.MaxSize = 1
+		})
+		It("should rotate the file", func() {
+			large := alerts[0]
+			large.Message = strings.Repeat("x", 700*1024)
+			send(large, large)
+			Expect(readLines(cfg.Path)).Should(HaveLen(1))
+			rotated, err := filepath.Glob(filepath.Join(dir, "alerts-*.jsonl"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rotated).Should(HaveLen(1))
+		})
+	})
+
+	When("file cannot be opened", func() {
+		It("should return an error", func() {
+			cfg.Path = filepath.Join(dir, "missing", "alerts.jsonl")
+			_, err := alert.NewJSONLinesNotifier(cfg)
+			Expect(err).Should(MatchError(ContainSubstring("failed to open")))
+		})
+	})
+})
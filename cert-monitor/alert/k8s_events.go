@@ -0,0 +1,283 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// ReasonCertificateExpiring is the reason of the events recorded for the certificates about to expire
+	ReasonCertificateExpiring = "CertificateExpiring"
+	// ReasonCertificateExpired is the reason of the events recorded for the expired certificates
+	ReasonCertificateExpired = "CertificateExpired"
+	// DefaultEventsComponent is the component reporting the events when none is configured
+	DefaultEventsComponent = "cert-monitor"
+	// DefaultEventsTimeout is the timeout of the event writes when none is configured
+	DefaultEventsTimeout = 10 * time.Second
+)
+
+// EventRecorder records k8s events on the objects
+type EventRecorder interface {
+	// Event records asynchronously an event of the type with the reason and the message on the object
+	Event(object ObjectRef, eventType, reason, message string)
+	// Close waits for the recorded events to be written and returns the failures
+	Close() error
+}
+
+// NewKubernetesEventRecorder returns an EventRecorder writing the events with the k8s API through a client-go event
+// broadcaster, which correlates and aggregates the events of an object. An event written by a previous run on the same
+// object with the same reason is updated and its count increased instead of creating a new one.
+func NewKubernetesEventRecorder(client corev1client.EventsGetter, cfg KubernetesEventsConfig) EventRecorder {
+	if cfg.Component == "" {
+		cfg.Component = DefaultEventsComponent
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultEventsTimeout
+	}
+	sink := &eventSink{
+		cfg:      cfg,
+		client:   client,
+		previous: map[string]map[string]*corev1.Event{},
+		results:  map[*corev1.Event]error{},
+		progress: make(chan struct{}, 1),
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(sink)
+	return &k8sEventRecorder{
+		cfg:         cfg,
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: cfg.Component}),
+		sink:        sink,
+	}
+}
+
+type k8sEventRecorder struct {
+	cfg         KubernetesEventsConfig
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	sink        *eventSink
+}
+
+// Event implements EventRecorder contract
+func (k *k8sEventRecorder) Event(object ObjectRef, eventType, reason, message string) {
+	k.sink.expect()
+	k.recorder.Event(&corev1.ObjectReference{
+		Kind:       object.Kind,
+		APIVersion: object.APIVersion,
+		Name:       object.Name,
+		Namespace:  object.Namespace,
+		UID:        types.UID(object.UID),
+	}, eventType, reason, message)
+}
+
+// Close implements EventRecorder contract, it fails when no event is written during the timeout
+func (k *k8sEventRecorder) Close() error {
+	k.broadcaster.Shutdown()
+	return k.sink.wait(k.cfg.Timeout)
+}
+
+// eventSink writes the events of the broadcaster with the k8s API and tracks the results of the writes
+type eventSink struct {
+	cfg    KubernetesEventsConfig
+	client corev1client.EventsGetter
+
+	mu sync.Mutex
+	// previous contains the events of the component per namespace, indexed by eventKey, they are listed once
+	previous map[string]map[string]*corev1.Event
+	// expected is the number of recorded events
+	expected int
+	// results contains the error of the last write of each event, nil when it succeeded
+	results map[*corev1.Event]error
+	// progress is notified after each write
+	progress chan struct{}
+}
+
+// Create implements record.EventSink contract, the event written by a previous run on the same object with the same
+// reason is updated instead
+func (s *eventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	previous, err := s.previousEvent(ctx, event)
+	if err != nil {
+		return nil, s.done(event, err)
+	}
+	var result *corev1.Event
+	if previous != nil {
+		data, err := json.Marshal(map[string]interface{}{
+			"count":         previous.Count + 1,
+			"lastTimestamp": event.LastTimestamp,
+			"message":       event.Message,
+		})
+		if err != nil {
+			return nil, s.done(event, fmt.Errorf("failed to marshal event patch: %w", err))
+		}
+		result, err = s.client.Events(previous.Namespace).Patch(ctx, previous.Name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	} else {
+		result, err = s.client.Events(event.Namespace).Create(ctx, event, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, s.done(event, err)
+	}
+	s.mu.Lock()
+	s.previous[event.Namespace][eventKey(result)] = result
+	s.mu.Unlock()
+	return result, s.done(event, nil)
+}
+
+// Update implements record.EventSink contract
+func (s *eventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	result, err := s.client.Events(event.Namespace).Update(ctx, event, metav1.UpdateOptions{})
+	return result, s.done(event, err)
+}
+
+// Patch implements record.EventSink contract
+func (s *eventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	result, err := s.client.Events(event.Namespace).Patch(ctx, event.Name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	return result, s.done(event, err)
+}
+
+// previousEvent returns the event of the component on the same object with the same reason, nil when there is none.
+// The events of the namespace are listed on the first call.
+func (s *eventSink) previousEvent(ctx context.Context, event *corev1.Event) (*corev1.Event, error) {
+	s.mu.Lock()
+	events, ok := s.previous[event.Namespace]
+	s.mu.Unlock()
+	if !ok {
+		list, err := s.client.Events(event.Namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("source", s.cfg.Component).String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events: %w", err)
+		}
+		events = map[string]*corev1.Event{}
+		for i := range list.Items {
+			events[eventKey(&list.Items[i])] = &list.Items[i]
+		}
+		s.mu.Lock()
+		s.previous[event.Namespace] = events
+		s.mu.Unlock()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return events[eventKey(event)], nil
+}
+
+// eventKey identifies the events of an object with the same type and reason
+func eventKey(event *corev1.Event) string {
+	object := event.InvolvedObject
+	return fmt.Sprintf("%s/%s/%s/%s/%s", object.APIVersion, object.Kind, object.Name, event.Type, event.Reason)
+}
+
+// expect counts a recorded event
+func (s *eventSink) expect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expected++
+}
+
+// done stores the result of the write of the event and returns err
+func (s *eventSink) done(event *corev1.Event, err error) error {
+	s.mu.Lock()
+	if err != nil {
+		s.results[event] = fmt.Errorf("failed to record event on %s/%s: %w", event.InvolvedObject.Namespace, event.InvolvedObject.Name, err)
+	} else {
+		s.results[event] = nil
+	}
+	s.mu.Unlock()
+	select {
+	case s.progress <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// wait waits until all the recorded events are written, it fails when no event is written during the timeout
+func (s *eventSink) wait(timeout time.Duration) error {
+	for {
+		s.mu.Lock()
+		written := len(s.results)
+		var err error
+		for _, result := range s.results {
+			err = multierr.Append(err, result)
+		}
+		s.mu.Unlock()
+		if written >= s.expected {
+			return err
+		}
+		select {
+		case <-s.progress:
+		case <-time.After(timeout):
+			return multierr.Append(err, fmt.Errorf("failed to record %d events: timeout", s.expected-written))
+		}
+	}
+}
+
+// NewKubernetesEventsNotifier returns a Notifier recording a Warning event on the object designated by the alert, the
+// INFO alerts are ignored
+func NewKubernetesEventsNotifier(recorder EventRecorder) Notifier {
+	return &k8sEventsNotifier{
+		recorder: recorder,
+	}
+}
+
+type k8sEventsNotifier struct {
+	recorder EventRecorder
+}
+
+// Send implements Notifier contract
+func (k *k8sEventsNotifier) Send(alert Alert) error {
+	var reason string
+	switch alert.Level {
+	case Error:
+		reason = ReasonCertificateExpired
+	case Warn:
+		reason = ReasonCertificateExpiring
+	default:
+		return nil
+	}
+	k.recorder.Event(alert.ObjectRef, corev1.EventTypeWarning, reason, fmt.Sprintf("%s, expiry: %s", alert.Message, expiry(alert)))
+	return nil
+}
+
+// Close implements Notifier contract, it waits for the events to be written
+func (k *k8sEventsNotifier) Close() error {
+	if err := k.recorder.Close(); err != nil {
+		return fmt.Errorf("failed to record events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("KubernetesEvents", func() {
+	var (
+		ref = alert.ObjectRef{
+			Name:       "cert",
+			Namespace:  "ns",
+			Kind:       "Certificate",
+			APIVersion: "cert-manager.io/v1",
+			UID:        "uid",
+		}
+		expiration = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	)
+
+	Describe("Notifier", func() {
+		var (
+			recorderMock *mocks.EventRecorder
+			notifier     alert.Notifier
+		)
+
+		BeforeEach(func() {
+			recorderMock = &mocks.EventRecorder{}
+			notifier = alert.NewKubernetesEventsNotifier(recorderMock)
+		})
+
+		AfterEach(func() {
+			recorderMock.AssertExpectations(GinkgoT())
+		})
+
+		It("should record a warning for the expired certificates", func() {
+			recorderMock.On("Event", ref, "Warning", "CertificateExpired",
+				"certificate expired, expiry: 2022-01-01T00:00:00Z").Once()
+			Expect(notifier.Send(alert.Alert{
+				Level:      alert.Error,
+				Message:    "certificate expired",
+				ObjectRef:  ref,
+				Expiration: expiration,
+			})).Should(Succeed())
+		})
+
+		It("should record a warning for the certificates about to expire", func() {
+			recorderMock.On("Event", ref, "Warning", "CertificateExpiring",
+				"certificate is about to expire, expiry: 2022-01-01T00:00:00Z").Once()
+			Expect(notifier.Send(alert.Alert{
+				Level:      alert.Warn,
+				Message:    "certificate is about to expire",
+				ObjectRef:  ref,
+				Expiration: expiration,
+			})).Should(Succeed())
+		})
+
+		It("should ignore the valid certificates", func() {
+			Expect(notifier.Send(alert.Alert{Level: alert.Info, ObjectRef: ref})).Should(Succeed())
+		})
+
+		It("should return an error when the events are not recorded", func() {
+			recorderMock.On("Close").Return(errors.New("forbidden")).Once()
+			Expect(notifier.Close()).Should(MatchError("failed to record events: forbidden"))
+		})
+	})
+
+	Describe("Recorder", func() {
+		var (
+			server *httptest.Server
+			// previous contains the events returned by the list of the events
+			previous []corev1.Event
+			requests []string
+			events   []corev1.Event
+			patches  []map[string]interface{}
+			status   int
+
+			recorder alert.EventRecorder
+		)
+
+		BeforeEach(func() {
+			previous = nil
+			requests = nil
+			events = nil
+			patches = nil
+			status = http.StatusCreated
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				switch r.Method {
+				case http.MethodGet:
+					Expect(r.URL.Query().Get("fieldSelector")).Should(Equal("source=" + alert.DefaultEventsComponent))
+					Expect(json.NewEncoder(w).Encode(corev1.EventList{Items: previous})).Should(Succeed())
+				case http.MethodPost:
+					var event corev1.Event
+					Expect(json.NewDecoder(r.Body).Decode(&event)).Should(Succeed())
+					events = append(events, event)
+					w.WriteHeader(status)
+					if status == http.StatusCreated {
+						Expect(json.NewEncoder(w).Encode(event)).Should(Succeed())
+					} else {
+						_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","message":"forbidden","reason":"Forbidden","code":403}`))
+					}
+				case http.MethodPatch:
+					Expect(r.Header.Get("Content-Type")).Should(Equal("application/strategic-merge-patch+json"))
+					var patch map[string]interface{}
+					Expect(json.NewDecoder(r.Body).Decode(&patch)).Should(Succeed())
+					patches = append(patches, patch)
+					event := corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: path.Base(r.URL.Path), Namespace: "ns"}}
+					Expect(json.NewEncoder(w).Encode(event)).Should(Succeed())
+				}
+			}))
+			clientSet, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+			Expect(err).ShouldNot(HaveOccurred())
+			recorder = alert.NewKubernetesEventRecorder(clientSet.CoreV1(), alert.KubernetesEventsConfig{Timeout: time.Second})
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should create the event on the object", func() {
+			recorder.Event(ref, "Warning", "CertificateExpired", "certificate expired")
+			Expect(recorder.Close()).Should(Succeed())
+			Expect(requests).Should(Equal([]string{"GET /api/v1/namespaces/ns/events", "POST /api/v1/namespaces/ns/events"}))
+			Expect(events).Should(HaveLen(1))
+			event := events[0]
+			Expect(event.Namespace).Should(Equal("ns"))
+			Expect(event.Name).Should(HavePrefix("cert."))
+			Expect(event.InvolvedObject).Should(Equal(corev1.ObjectReference{
+				Kind:       "Certificate",
+				APIVersion: "cert-manager.io/v1",
+				Name:       "cert",
+				Namespace:  "ns",
+				UID:        "uid",
+			}))
+			Expect(event.Type).Should(Equal("Warning"))
+			Expect(event.Reason).Should(Equal("CertificateExpired"))
+			Expect(event.Message).Should(Equal("certificate expired"))
+			Expect(event.Count).Should(BeEquivalentTo(1))
+			Expect(event.Source.Component).Should(Equal(alert.DefaultEventsComponent))
+		})
+
+		It("should increase the count of the event recorded by a previous run", func() {
+			previous = []corev1.Event{{
+				ObjectMeta: metav1.ObjectMeta{Name: "cert.16c2d4e0b1f5a9c0", Namespace: "ns"},
+				InvolvedObject: corev1.ObjectReference{
+					Kind:       "Certificate",
+					APIVersion: "cert-manager.io/v1",
+					Name:       "cert",
+					Namespace:  "ns",
+				},
+				Type:   "Warning",
+				Reason: "CertificateExpired",
+				Count:  3,
+			}}
+			recorder.Event(ref, "Warning", "CertificateExpired", "certificate expired")
+			Expect(recorder.Close()).Should(Succeed())
+			Expect(events).Should(BeEmpty())
+			Expect(requests).Should(ContainElement("PATCH /api/v1/namespaces/ns/events/cert.16c2d4e0b1f5a9c0"))
+			Expect(patches).Should(HaveLen(1))
+			Expect(patches[0]).Should(HaveKeyWithValue("count", BeEquivalentTo(4)))
+			Expect(patches[0]).Should(HaveKeyWithValue("message", "certificate expired"))
+		})
+
+		It("should correlate the events recorded in the same run", func() {
+			recorder.Event(ref, "Warning", "CertificateExpired", "certificate expired")
+			recorder.Event(ref, "Warning", "CertificateExpired", "certificate expired")
+			Expect(recorder.Close()).Should(Succeed())
+			Expect(events).Should(HaveLen(1))
+			Expect(patches).Should(HaveLen(1))
+			Expect(patches[0]).Should(HaveKeyWithValue("count", BeEquivalentTo(2)))
+		})
+
+		It("should return an error when the API rejects the event", func() {
+			status = http.StatusForbidden
+			recorder.Event(ref, "Warning", "CertificateExpired", "certificate expired")
+			Expect(recorder.Close()).Should(MatchError("failed to record event on ns/cert: forbidden"))
+		})
+	})
+})
@@ -20,42 +20,134 @@
 package alert
 
 import (
-	"encoding/json"
 	"fmt"
 
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/version"
+
 	"github.com/Shopify/sarama"
 )
 
-func NewKafkaNotifier(topic string, producer sarama.SyncProducer) Notifier {
+const (
+	// SchemaVersion is the version of the schema of the alert as produced in kafka
+	SchemaVersion = "5"
+
+	// LevelHeader is the kafka header containing the level of the alert
+	LevelHeader = "level"
+	// SchemaVersionHeader is the kafka header containing the version of the schema of the message value
+	SchemaVersionHeader = "schema-version"
+	// ContentTypeHeader is the kafka header containing the content type of the message value
+	ContentTypeHeader = "content-type"
+	// ProducerVersionHeader is the kafka header containing the version of the cert-monitor that produced the message
+	ProducerVersionHeader = "producer-version"
+)
+
+// NewKafkaNotifier returns a BatchNotifier that produces the alerts encoded with the encoder in the configured kafka
+// topic
+func NewKafkaNotifier(cfg KafkaConfig, encoder Encoder, producer sarama.SyncProducer) BatchNotifier {
 	return &kafkaNotifier{
-		topic: topic,
+		builder:  messageBuilder{cfg: cfg, encoder: encoder},
 		producer: producer,
 	}
 }
 
 type kafkaNotifier struct {
-	topic    string
+	builder  messageBuilder
 	producer sarama.SyncProducer
 }
 
 func (k *kafkaNotifier) Send(alert Alert) error {
-	data, err := json.Marshal(alert)
+	msg, err := k.builder.build(alert)
 	if err != nil {
-		return fmt.Errorf("failed to marshal alert in JSON: %w", err)
+		return err
 	}
-	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
-		Topic: k.topic,
-		Value: sarama.ByteEncoder(data),
-	})
+	_, _, err = k.producer.SendMessage(msg)
 	if err != nil {
 		return fmt.Errorf("failed to deliver alert: %w", err)
 	}
 	return nil
 }
 
+// SendBatch produces all the alerts in a single call to the producer
+func (k *kafkaNotifier) SendBatch(alerts []Alert) error {
+	msgs := make([]*sarama.ProducerMessage, 0, len(alerts))
+	for _, alert := range alerts {
+		msg, err := k.builder.build(alert)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := k.producer.SendMessages(msgs); err != nil {
+		return fmt.Errorf("failed to deliver alerts: %w", err)
+	}
+	return nil
+}
+
 func (k *kafkaNotifier) Close() error {
 	if err := k.producer.Close(); err != nil {
 		return fmt.Errorf("failed to close producer: %w", err)
 	}
 	return nil
 }
+
+// messageBuilder converts the alerts to kafka messages
+type messageBuilder struct {
+	cfg     KafkaConfig
+	encoder Encoder
+}
+
+func (b messageBuilder) build(alert Alert) (*sarama.ProducerMessage, error) {
+	data, err := b.encoder.Encode(alert)
+	if err != nil {
+		return nil, err
+	}
+	headers := b.headers(alert)
+	switch b.cfg.CloudEvents {
+	case "":
+		headers = append(headers, header(ContentTypeHeader, b.encoder.ContentType()))
+	case CloudEventsStructured:
+		event := NewCloudEvent(b.cfg.Cluster, alert, b.encoder.ContentType())
+		if data, err = event.Structured(data); err != nil {
+			return nil, err
+		}
+		headers = append(headers, header(ContentTypeHeader, CloudEventsContentType))
+	case CloudEventsBinary:
+		event := NewCloudEvent(b.cfg.Cluster, alert, b.encoder.ContentType())
+		// the data content type is carried by the content-type header in the kafka protocol binding
+		headers = append(headers, header(ContentTypeHeader, b.encoder.ContentType()))
+		attributes := event.Attributes()
+		for _, name := range []string{"specversion", "id", "source", "type", "subject", "time"} {
+			headers = append(headers, header("ce_"+name, attributes[name]))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CloudEvents mode %q", b.cfg.CloudEvents)
+	}
+	return &sarama.ProducerMessage{
+		Topic:   b.cfg.Topic,
+		Key:     sarama.StringEncoder(b.key(alert)),
+		Value:   sarama.ByteEncoder(data),
+		Headers: headers,
+	}, nil
+}
+
+// key returns the message key of the alert, all the alerts about the same certificate share the same key so they land
+// in the same partition and keep their ordering
+func (b messageBuilder) key(alert Alert) string {
+	key := alert.ObjectRef.Namespace + "/" + alert.ObjectRef.Name
+	if b.cfg.KeyIncludesCluster {
+		key = b.cfg.Cluster + "/" + key
+	}
+	return key
+}
+
+func (b messageBuilder) headers(alert Alert) []sarama.RecordHeader {
+	return []sarama.RecordHeader{
+		header(LevelHeader, alert.Level.String()),
+		header(SchemaVersionHeader, SchemaVersion),
+		header(ProducerVersionHeader, version.Version),
+	}
+}
+
+func header(key, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"go.uber.org/multierr"
+)
+
+// ErrNotifierClosed is returned when an alert is sent to a closed notifier
+var ErrNotifierClosed = errors.New("notifier is closed")
+
+// DefaultMaxInFlight is the default maximum number of alerts waiting to be delivered by the asynchronous producer
+const DefaultMaxInFlight = 256
+
+// NewAsyncKafkaNotifier returns an AsyncNotifier that produces the alerts encoded with the encoder in the configured
+// kafka topic without waiting for their delivery. The producer must be configured to return successes and errors, they are consumed in
+// background to track the deliveries.
+func NewAsyncKafkaNotifier(cfg KafkaConfig, encoder Encoder, producer sarama.AsyncProducer) AsyncNotifier {
+	maxInFlight := cfg.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	idle := make(chan struct{})
+	close(idle)
+	k := &asyncKafkaNotifier{
+		builder:  messageBuilder{cfg: cfg, encoder: encoder},
+		producer: producer,
+		inFlight: make(chan struct{}, maxInFlight),
+		idle:     idle,
+	}
+	k.drained.Add(2)
+	go k.drainSuccesses()
+	go k.drainErrors()
+	return k
+}
+
+type asyncKafkaNotifier struct {
+	builder  messageBuilder
+	producer sarama.AsyncProducer
+
+	// inFlight bounds the number of alerts waiting to be delivered
+	inFlight chan struct{}
+	// drained is done when the successes and errors channels of the producer are drained
+	drained sync.WaitGroup
+	// sending is done when no alert is being written to the input channel of the producer
+	sending sync.WaitGroup
+
+	mu sync.Mutex
+	// closed is true once Close is called, the input channel of the producer must not be written anymore
+	closed bool
+	// pending is the number of alerts waiting to be delivered
+	pending int
+	// idle is closed when there is no pending alert
+	idle chan struct{}
+	// failures contains the delivery failures since the last flush
+	failures []error
+}
+
+// Send implements Notifier contract, it blocks when the maximum number of alerts in flight is reached and returns
+// ErrNotifierClosed once the notifier is closed
+func (k *asyncKafkaNotifier) Send(alert Alert) error {
+	msg, err := k.builder.build(alert)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return fmt.Errorf("failed to send alert %s: %w", k.builder.key(alert), ErrNotifierClosed)
+	}
+	k.sending.Add(1)
+	k.mu.Unlock()
+	defer k.sending.Done()
+
+	k.inFlight <- struct{}{}
+	k.track()
+	k.producer.Input() <- msg
+	return nil
+}
+
+// Flush implements AsyncNotifier contract
+func (k *asyncKafkaNotifier) Flush(ctx context.Context) error {
+	k.mu.Lock()
+	idle := k.idle
+	k.mu.Unlock()
+
+	// a finished delivery is reported even when the context is already done
+	select {
+	case <-idle:
+		return k.takeFailures()
+	default:
+	}
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for alerts delivery: %w", ctx.Err())
+	}
+	return k.takeFailures()
+}
+
+// Close implements Notifier contract, the pending alerts are delivered before closing the producer
+func (k *asyncKafkaNotifier) Close() error {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return ErrNotifierClosed
+	}
+	k.closed = true
+	k.mu.Unlock()
+
+	k.sending.Wait()
+	k.producer.AsyncClose()
+	k.drained.Wait()
+	if err := k.takeFailures(); err != nil {
+		return fmt.Errorf("failed to close producer: %w", err)
+	}
+	return nil
+}
+
+func (k *asyncKafkaNotifier) drainSuccesses() {
+	defer k.drained.Done()
+	for range k.producer.Successes() {
+		k.delivered(nil)
+	}
+}
+
+func (k *asyncKafkaNotifier) drainErrors() {
+	defer k.drained.Done()
+	for perr := range k.producer.Errors() {
+		var key string
+		if perr.Msg != nil && perr.Msg.Key != nil {
+			data, _ := perr.Msg.Key.Encode()
+			key = string(data)
+		}
+		k.delivered(fmt.Errorf("failed to deliver alert %s: %w", key, perr.Err))
+	}
+}
+
+func (k *asyncKafkaNotifier) track() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.pending == 0 {
+		k.idle = make(chan struct{})
+	}
+	k.pending++
+}
+
+func (k *asyncKafkaNotifier) delivered(err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err != nil {
+		k.failures = append(k.failures, err)
+	}
+	k.pending--
+	if k.pending == 0 {
+		close(k.idle)
+	}
+	<-k.inFlight
+}
+
+func (k *asyncKafkaNotifier) takeFailures() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	err := multierr.Combine(k.failures...)
+	k.failures = nil
+	return err
+}
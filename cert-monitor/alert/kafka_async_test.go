@@ -0,0 +1,161 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AsyncKafka", func() {
+	var (
+		kafkaNotifier alert.AsyncNotifier
+		producerMock  *mocks.AsyncProducer
+
+		a = alert.Alert{
+			Level:   alert.Error,
+			Message: "This is fine",
+			ObjectRef: alert.ObjectRef{
+				Name:      "cert",
+				Namespace: "ns",
+			},
+			Source: "UT",
+		}
+
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg := mocks.NewTestConfig()
+		cfg.Producer.Return.Successes = true
+		producerMock = mocks.NewAsyncProducer(GinkgoT(), cfg)
+		kafkaNotifier = alert.NewAsyncKafkaNotifier(alert.KafkaConfig{
+			Topic:       "topic",
+			MaxInFlight: 1,
+		}, alert.NewJSONEncoder(), producerMock)
+	})
+
+	Describe("Flush", func() {
+		JustBeforeEach(func() {
+			Expect(kafkaNotifier.Send(a)).Should(Succeed())
+			Expect(kafkaNotifier.Send(a)).Should(Succeed())
+			err = kafkaNotifier.Flush(context.Background())
+		})
+
+		AfterEach(func() {
+			Expect(kafkaNotifier.Close()).Should(Succeed())
+		})
+
+		When("alerts are delivered", func() {
+			BeforeEach(func() {
+				producerMock.ExpectInputAndSucceed()
+				producerMock.ExpectInputAndSucceed()
+			})
+			It("should not return any errors", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		When("alerts failed to be delivered", func() {
+			BeforeEach(func() {
+				producerMock.ExpectInputAndSucceed()
+				producerMock.ExpectInputAndFail(errors.New("broker is down"))
+			})
+			It("should return the delivery failures", func() {
+				Expect(err).Should(MatchError("failed to deliver alert ns/cert: broker is down"))
+			})
+			It("should report the failures only once", func() {
+				Expect(kafkaNotifier.Flush(context.Background())).Should(Succeed())
+			})
+		})
+	})
+
+	Describe("Flush with a cancelled context", func() {
+		It("should return an error when alerts are still pending", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			producerMock.ExpectInputWithMessageCheckerFunctionAndSucceed(func(*sarama.ProducerMessage) error {
+				Expect(kafkaNotifier.Flush(ctx)).Should(MatchError("failed to wait for alerts delivery: context canceled"))
+				return nil
+			})
+			Expect(kafkaNotifier.Send(a)).Should(Succeed())
+			Expect(kafkaNotifier.Close()).Should(Succeed())
+		})
+
+		It("should report the delivery when alerts are delivered", func() {
+			producerMock.ExpectInputAndFail(errors.New("broker is down"))
+			Expect(kafkaNotifier.Send(a)).Should(Succeed())
+			Expect(kafkaNotifier.Flush(context.Background())).Should(MatchError("failed to deliver alert ns/cert: broker is down"))
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			// the selection must not pick the cancelled context once the alerts are delivered
+			for i := 0; i < 100; i++ {
+				Expect(kafkaNotifier.Flush(ctx)).Should(Succeed())
+			}
+			Expect(kafkaNotifier.Close()).Should(Succeed())
+		})
+	})
+
+	Describe("Close", func() {
+		JustBeforeEach(func() {
+			Expect(kafkaNotifier.Send(a)).Should(Succeed())
+			err = kafkaNotifier.Close()
+		})
+
+		When("pending alert is delivered", func() {
+			BeforeEach(func() {
+				producerMock.ExpectInputAndSucceed()
+			})
+			It("should not return any errors", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		When("pending alert failed to be delivered", func() {
+			BeforeEach(func() {
+				producerMock.ExpectInputAndFail(errors.New("broker is down"))
+			})
+			It("should return the delivery failure", func() {
+				Expect(err).Should(MatchError("failed to close producer: failed to deliver alert ns/cert: broker is down"))
+			})
+		})
+
+		When("notifier is closed", func() {
+			BeforeEach(func() {
+				producerMock.ExpectInputAndSucceed()
+			})
+			It("should reject the alerts", func() {
+				err = kafkaNotifier.Send(a)
+				Expect(errors.Is(err, alert.ErrNotifierClosed)).Should(BeTrue())
+				Expect(err).Should(MatchError("failed to send alert ns/cert: notifier is closed"))
+			})
+			It("should not close the producer twice", func() {
+				Expect(kafkaNotifier.Close()).Should(MatchError(alert.ErrNotifierClosed))
+			})
+		})
+	})
+})
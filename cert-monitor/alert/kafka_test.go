@@ -23,7 +23,9 @@ import (
 	"errors"
 
 	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/version"
 
+	"github.com/Shopify/sarama"
 	"github.com/Shopify/sarama/mocks"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -33,13 +35,21 @@ var _ = Describe("Kafka", func() {
 	var (
 		kafkaNotifier alert.Notifier
 		producerMock  *mocks.SyncProducer
+		cfg           alert.KafkaConfig
 
 		err error
 	)
 
 	BeforeEach(func() {
 		producerMock = mocks.NewSyncProducer(GinkgoT(), mocks.NewTestConfig())
-		kafkaNotifier = alert.NewKafkaNotifier("topic", producerMock)
+		cfg = alert.KafkaConfig{
+			Topic:   "topic",
+			Cluster: "cluster",
+		}
+	})
+
+	JustBeforeEach(func() {
+		kafkaNotifier = alert.NewKafkaNotifier(cfg, alert.NewJSONEncoder(), producerMock)
 	})
 
 	AfterEach(func() {
@@ -73,6 +83,80 @@ var _ = Describe("Kafka", func() {
 			})
 		})
 
+		When("message is produced", func() {
+			var msg *sarama.ProducerMessage
+			BeforeEach(func() {
+				producerMock.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(m *sarama.ProducerMessage) error {
+					msg = m
+					return nil
+				})
+			})
+			It("should be keyed by namespace and name", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(msg.Key).Should(Equal(sarama.StringEncoder("ns/cert")))
+			})
+			It("should have headers describing the alert", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(msg.Headers).Should(ConsistOf(
+					sarama.RecordHeader{Key: []byte("level"), Value: []byte("ERROR")},
+					sarama.RecordHeader{Key: []byte("schema-version"), Value: []byte(alert.SchemaVersion)},
+					sarama.RecordHeader{Key: []byte("content-type"), Value: []byte("application/json")},
+					sarama.RecordHeader{Key: []byte("producer-version"), Value: []byte(version.Version)},
+				))
+			})
+
+			When("alert is wrapped in a structured CloudEvent", func() {
+				BeforeEach(func() {
+					cfg.CloudEvents = alert.CloudEventsStructured
+				})
+				It("should produce the event", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					value, _ := msg.Value.Encode()
+					Expect(value).Should(ContainSubstring(`"type":"io.cert-monitor.certificate.expiring"`))
+					Expect(value).Should(ContainSubstring(`"data":{"level":"ERROR"`))
+					Expect(msg.Headers).Should(ContainElement(
+						sarama.RecordHeader{Key: []byte("content-type"), Value: []byte("application/cloudevents+json")}))
+				})
+			})
+
+			When("alert is wrapped in a binary CloudEvent", func() {
+				BeforeEach(func() {
+					cfg.CloudEvents = alert.CloudEventsBinary
+				})
+				It("should carry the attributes in the headers", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					value, _ := msg.Value.Encode()
+					Expect(value).Should(HavePrefix(`{"level":"ERROR"`))
+					Expect(msg.Headers).Should(ContainElements(
+						sarama.RecordHeader{Key: []byte("content-type"), Value: []byte("application/json")},
+						sarama.RecordHeader{Key: []byte("ce_specversion"), Value: []byte("1.0")},
+						sarama.RecordHeader{Key: []byte("ce_type"), Value: []byte("io.cert-monitor.certificate.expiring")},
+						sarama.RecordHeader{Key: []byte("ce_source"), Value: []byte("/cluster/UT")},
+						sarama.RecordHeader{Key: []byte("ce_subject"), Value: []byte("ns/cert")},
+					))
+				})
+			})
+
+			When("key includes cluster", func() {
+				BeforeEach(func() {
+					cfg.KeyIncludesCluster = true
+				})
+				It("should be keyed by cluster, namespace and name", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(msg.Key).Should(Equal(sarama.StringEncoder("cluster/ns/cert")))
+				})
+			})
+		})
+
+		When("CloudEvents mode is unknown", func() {
+			BeforeEach(func() {
+				cfg.CloudEvents = "whatever"
+			})
+			It("should return an error", func() {
+				Expect(err).Should(MatchError(`unsupported CloudEvents mode "whatever"`))
+			})
+		})
+
 		When("producer failed to send message", func() {
 			criticalError := errors.New("broker is down")
 			BeforeEach(func() {
@@ -85,6 +169,66 @@ var _ = Describe("Kafka", func() {
 
 	})
 
+	Describe("SendBatch", func() {
+		var (
+			alerts = []alert.Alert{
+				{
+					Level:   alert.Error,
+					Message: "certificate expired",
+					ObjectRef: alert.ObjectRef{
+						Name:      "cert1",
+						Namespace: "ns",
+					},
+					Source: "UT",
+				},
+				{
+					Level:   alert.Warn,
+					Message: "certificate is about to expire",
+					ObjectRef: alert.ObjectRef{
+						Name:      "cert2",
+						Namespace: "ns",
+					},
+					Source: "UT",
+				},
+			}
+		)
+
+		JustBeforeEach(func() {
+			err = kafkaNotifier.(alert.BatchNotifier).SendBatch(alerts)
+		})
+
+		When("messages can be sent", func() {
+			var keys []sarama.Encoder
+			BeforeEach(func() {
+				keys = nil
+				checker := func(m *sarama.ProducerMessage) error {
+					keys = append(keys, m.Key)
+					return nil
+				}
+				producerMock.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checker)
+				producerMock.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checker)
+			})
+			It("should produce all the alerts", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(keys).Should(Equal([]sarama.Encoder{
+					sarama.StringEncoder("ns/cert1"),
+					sarama.StringEncoder("ns/cert2"),
+				}))
+			})
+		})
+
+		When("producer failed to send messages", func() {
+			criticalError := errors.New("broker is down")
+			BeforeEach(func() {
+				producerMock.ExpectSendMessageAndSucceed()
+				producerMock.ExpectSendMessageAndFail(criticalError)
+			})
+			It("should return an error", func() {
+				Expect(err).Should(MatchError("failed to deliver alerts: broker is down"))
+			})
+		})
+	})
+
 	Describe("Close", func() {
 
 		JustBeforeEach(func() {
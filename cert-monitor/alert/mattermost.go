@@ -0,0 +1,115 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import "fmt"
+
+// NewMattermostNotifier returns a BatchNotifier that posts the alerts to a Mattermost incoming webhook as message
+// attachments. The alerts of a batch are aggregated in a single digest message when their number exceeds the digest
+// threshold.
+func NewMattermostNotifier(cfg MattermostConfig) (BatchNotifier, error) {
+	formatter, err := newChatFormatter(cfg.ChatConfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Mattermost client: %w", err)
+	}
+	return &mattermostNotifier{
+		cfg:       cfg,
+		formatter: formatter,
+		client:    client,
+	}, nil
+}
+
+type mattermostNotifier struct {
+	cfg       MattermostConfig
+	formatter *chatFormatter
+	client    *httpClient
+}
+
+type mattermostMessage struct {
+	Channel     string                 `json:"channel,omitempty"`
+	Username    string                 `json:"username,omitempty"`
+	Text        string                 `json:"text"`
+	Attachments []mattermostAttachment `json:"attachments"`
+}
+
+type mattermostAttachment struct {
+	Fallback  string            `json:"fallback"`
+	Color     string            `json:"color"`
+	Title     string            `json:"title"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Fields    []mattermostField `json:"fields,omitempty"`
+}
+
+type mattermostField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Send implements Notifier contract
+func (m *mattermostNotifier) Send(alert Alert) error {
+	msg, err := m.formatter.alert(alert)
+	if err != nil {
+		return err
+	}
+	return m.post(msg)
+}
+
+// SendBatch implements BatchNotifier contract
+func (m *mattermostNotifier) SendBatch(alerts []Alert) error {
+	return m.formatter.send(alerts, m.post)
+}
+
+// Close implements Notifier contract
+func (m *mattermostNotifier) Close() error {
+	return nil
+}
+
+// message renders the chat message as a Mattermost attachment
+func (m *mattermostNotifier) message(msg chatMessage) mattermostMessage {
+	attachment := mattermostAttachment{
+		Fallback:  msg.Text(),
+		Color:     levelColor(msg.Level),
+		Title:     msg.Text(),
+		TitleLink: msg.URL,
+	}
+	if msg.Digest() {
+		attachment.Text = markdownLines(msg)
+	} else {
+		for _, fact := range msg.Facts {
+			attachment.Fields = append(attachment.Fields, mattermostField{Title: fact.Name, Value: fact.Value, Short: true})
+		}
+	}
+	return mattermostMessage{
+		Channel:     m.cfg.Channel,
+		Username:    m.cfg.Username,
+		Text:        msg.Text(),
+		Attachments: []mattermostAttachment{attachment},
+	}
+}
+
+func (m *mattermostNotifier) post(msg chatMessage) error {
+	return postChatMessage(m.client, m.cfg.WebhookURL, "Mattermost", m.message(msg))
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"context"
+
+	"go.uber.org/multierr"
+)
+
+// NewMultiNotifier returns a Notifier that sends the alerts to all the notifiers, the alerts are sent to the remaining
+// notifiers when one of them fails
+func NewMultiNotifier(notifiers ...Notifier) Notifier {
+	return &multiNotifier{
+		notifiers: notifiers,
+	}
+}
+
+type multiNotifier struct {
+	notifiers []Notifier
+}
+
+// Send implements Notifier contract
+func (m *multiNotifier) Send(alert Alert) error {
+	var err error
+	for _, n := range m.notifiers {
+		err = multierr.Append(err, n.Send(alert))
+	}
+	return err
+}
+
+// SendBatch implements BatchNotifier contract, the alerts are sent one by one to the notifiers not supporting batches
+func (m *multiNotifier) SendBatch(alerts []Alert) error {
+	var err error
+	for _, n := range m.notifiers {
+		if batchNotifier, ok := n.(BatchNotifier); ok {
+			err = multierr.Append(err, batchNotifier.SendBatch(alerts))
+			continue
+		}
+		for _, alert := range alerts {
+			err = multierr.Append(err, n.Send(alert))
+		}
+	}
+	return err
+}
+
+// Flush implements AsyncNotifier contract, only the asynchronous notifiers are flushed
+func (m *multiNotifier) Flush(ctx context.Context) error {
+	var err error
+	for _, n := range m.notifiers {
+		if asyncNotifier, ok := n.(AsyncNotifier); ok {
+			err = multierr.Append(err, asyncNotifier.Flush(ctx))
+		}
+	}
+	return err
+}
+
+// Close implements Notifier contract
+func (m *multiNotifier) Close() error {
+	var err error
+	for _, n := range m.notifiers {
+		err = multierr.Append(err, n.Close())
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MultiNotifier", func() {
+	var (
+		notifierMock      *mocks.Notifier
+		batchNotifierMock *mocks.BatchNotifier
+		asyncNotifierMock *mocks.AsyncNotifier
+		notifier          alert.Notifier
+
+		alerts = []alert.Alert{
+			{Level: alert.Warn, ObjectRef: alert.ObjectRef{Name: "cert1", Namespace: "ns"}},
+			{Level: alert.Error, ObjectRef: alert.ObjectRef{Name: "cert2", Namespace: "ns"}},
+		}
+	)
+
+	BeforeEach(func() {
+		notifierMock = &mocks.Notifier{}
+		batchNotifierMock = &mocks.BatchNotifier{}
+		asyncNotifierMock = &mocks.AsyncNotifier{}
+		notifier = alert.NewMultiNotifier(notifierMock, batchNotifierMock, asyncNotifierMock)
+	})
+
+	AfterEach(func() {
+		notifierMock.AssertExpectations(GinkgoT())
+		batchNotifierMock.AssertExpectations(GinkgoT())
+		asyncNotifierMock.AssertExpectations(GinkgoT())
+	})
+
+	Describe("SendBatch", func() {
+		It("should send the alerts to all the notifiers", func() {
+			notifierMock.On("Send", alerts[0]).Return(nil).Once()
+			notifierMock.On("Send", alerts[1]).Return(nil).Once()
+			batchNotifierMock.On("SendBatch", alerts).Return(nil).Once()
+			asyncNotifierMock.On("Send", alerts[0]).Return(nil).Once()
+			asyncNotifierMock.On("Send", alerts[1]).Return(nil).Once()
+			Expect(notifier.(alert.BatchNotifier).SendBatch(alerts)).Should(Succeed())
+		})
+
+		It("should keep sending when a notifier fails", func() {
+			notifierMock.On("Send", alerts[0]).Return(errors.New("boom")).Once()
+			notifierMock.On("Send", alerts[1]).Return(nil).Once()
+			batchNotifierMock.On("SendBatch", alerts).Return(errors.New("broker is down")).Once()
+			asyncNotifierMock.On("Send", alerts[0]).Return(nil).Once()
+			asyncNotifierMock.On("Send", alerts[1]).Return(nil).Once()
+			Expect(notifier.(alert.BatchNotifier).SendBatch(alerts)).Should(MatchError("boom; broker is down"))
+		})
+	})
+
+	Describe("Flush", func() {
+		It("should flush the asynchronous notifiers", func() {
+			asyncNotifierMock.On("Flush", context.TODO()).Return(errors.New("not delivered")).Once()
+			Expect(notifier.(alert.AsyncNotifier).Flush(context.TODO())).Should(MatchError("not delivered"))
+		})
+	})
+
+	Describe("Close", func() {
+		It("should close all the notifiers", func() {
+			notifierMock.On("Close").Return(nil).Once()
+			batchNotifierMock.On("Close").Return(nil).Once()
+			asyncNotifierMock.On("Close").Return(nil).Once()
+			Expect(notifier.Close()).Should(Succeed())
+		})
+	})
+})
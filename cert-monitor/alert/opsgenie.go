@@ -0,0 +1,244 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultOpsgenieURL is the URL of the Opsgenie API
+	DefaultOpsgenieURL = "https://api.opsgenie.com"
+	// maxOpsgenieMessageSize is the maximum number of characters of the message of an Opsgenie alert
+	maxOpsgenieMessageSize = 130
+	// opsgeniePageSize is the number of alerts listed per request
+	opsgeniePageSize = 100
+	// opsgenieTag is added to the alerts created by the notifier, the open alerts are listed by this tag
+	opsgenieTag = "cert-monitor"
+)
+
+// NewOpsgenieNotifier returns a Notifier that creates an Opsgenie alert for the WARN and ERROR alerts, and closes it
+// when an INFO alert reports that the certificate is valid again. The alerts are deduplicated by certificate with
+// their alias and tagged with cert-monitor. Only the open alerts having the tag, listed once, are closed.
+func NewOpsgenieNotifier(cfg OpsgenieConfig) (Notifier, error) {
+	apiKey, err := ioutil.ReadFile(cfg.APIKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Opsgenie API key: %w", err)
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Opsgenie client: %w", err)
+	}
+	baseURL := cfg.URL
+	if baseURL == "" {
+		baseURL = DefaultOpsgenieURL
+	}
+	o := &opsgenieNotifier{
+		cfg:     cfg,
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/v2/alerts",
+		apiKey:  strings.TrimSpace(string(apiKey)),
+		client:  client,
+	}
+	o.open = &openIncidents{list: o.listOpenAliases}
+	return o, nil
+}
+
+type opsgenieNotifier struct {
+	cfg     OpsgenieConfig
+	baseURL string
+	apiKey  string
+	client  *httpClient
+	open    *openIncidents
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details"`
+	Entity      string              `json:"entity"`
+	Source      string              `json:"source"`
+	Priority    string              `json:"priority"`
+}
+
+type opsgenieResponder struct {
+	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type opsgenieAlerts struct {
+	Data []struct {
+		Alias string `json:"alias"`
+	} `json:"data"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note"`
+}
+
+// Send implements Notifier contract, the INFO alerts of the certificates without an open alert and the alerts of
+// unknown level are ignored
+func (o *opsgenieNotifier) Send(alert Alert) error {
+	alias := incidentKey(o.cfg.Cluster, alert)
+	switch alert.Level {
+	case Info:
+		open, err := o.open.isOpen(alias)
+		if err != nil {
+			return fmt.Errorf("failed to close Opsgenie alert %s: %w", alias, err)
+		}
+		if !open {
+			return nil
+		}
+		closeURL := fmt.Sprintf("%s/%s/close?identifierType=alias", o.baseURL, url.PathEscape(alias))
+		return o.post(closeURL, opsgenieClose{Source: alert.Source, Note: alert.Message})
+	case Warn, Error:
+		summary := fmt.Sprintf("%s/%s: %s", alert.ObjectRef.Namespace, alert.ObjectRef.Name, alert.Message)
+		return o.post(o.baseURL, opsgenieAlert{
+			Message:     truncate(summary, maxOpsgenieMessageSize),
+			Alias:       alias,
+			Description: fmt.Sprintf("%s, %s (%s)", summary, remaining(alert), expiry(alert)),
+			Responders:  o.responders(),
+			Tags:        o.tags(),
+			Details: map[string]string{
+				"cluster":   o.cfg.Cluster,
+				"namespace": alert.ObjectRef.Namespace,
+				"name":      alert.ObjectRef.Name,
+				"expiry":    expiry(alert),
+				"remaining": remaining(alert),
+			},
+			Entity:   alert.ObjectRef.Namespace + "/" + alert.ObjectRef.Name,
+			Source:   alert.Source,
+			Priority: opsgeniePriority(alert.Level),
+		})
+	default:
+		return nil
+	}
+}
+
+// Close implements Notifier contract
+func (o *opsgenieNotifier) Close() error {
+	return nil
+}
+
+// responders returns the configured responders, users are identified by their username
+func (o *opsgenieNotifier) responders() []opsgenieResponder {
+	responders := make([]opsgenieResponder, 0, len(o.cfg.Responders))
+	for _, r := range o.cfg.Responders {
+		responder := opsgenieResponder{Type: r.Type, ID: r.ID}
+		if r.Type == "user" {
+			responder.Username = r.Name
+		} else {
+			responder.Name = r.Name
+		}
+		responders = append(responders, responder)
+	}
+	return responders
+}
+
+// tags returns the configured tags and the tag of the alerts of the notifier
+func (o *opsgenieNotifier) tags() []string {
+	tags := []string{opsgenieTag}
+	for _, tag := range o.cfg.Tags {
+		if tag != opsgenieTag {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// listOpenAliases returns the aliases of the open alerts created by the notifier
+func (o *opsgenieNotifier) listOpenAliases() (map[string]struct{}, error) {
+	aliases := map[string]struct{}{}
+	for offset := 0; ; offset += opsgeniePageSize {
+		query := url.Values{
+			"query":  {"status:open AND tag:" + opsgenieTag},
+			"limit":  {fmt.Sprint(opsgeniePageSize)},
+			"offset": {fmt.Sprint(offset)},
+		}
+		req, err := http.NewRequest(http.MethodGet, o.baseURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Opsgenie request: %w", err)
+		}
+		req.Header.Set("Authorization", "GenieKey "+o.apiKey)
+		body, err := o.client.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Opsgenie alerts: %w", err)
+		}
+		var page opsgenieAlerts
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Opsgenie alerts: %w", err)
+		}
+		for _, alert := range page.Data {
+			aliases[alert.Alias] = struct{}{}
+		}
+		if len(page.Data) < opsgeniePageSize {
+			return aliases, nil
+		}
+	}
+}
+
+func (o *opsgenieNotifier) post(target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Opsgenie request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Opsgenie request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+o.apiKey)
+	if _, err := o.client.do(req); err != nil {
+		return fmt.Errorf("failed to send Opsgenie request: %w", err)
+	}
+	return nil
+}
+
+// opsgeniePriority maps the level of the alert to an Opsgenie priority
+func opsgeniePriority(level Level) string {
+	switch level {
+	case Error:
+		return "P2"
+	case Warn:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
+
+// truncate returns the first size characters of s
+func truncate(s string, size int) string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return s
+	}
+	return string(runes[:size])
+}
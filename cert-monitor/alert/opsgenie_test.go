@@ -0,0 +1,185 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Opsgenie", func() {
+	var (
+		server   *httptest.Server
+		requests []*http.Request
+		bodies   []map[string]interface{}
+		// open contains the open alerts returned by the list of the alerts
+		open     string
+		listings []*http.Request
+
+		cfg      alert.OpsgenieConfig
+		notifier alert.Notifier
+		a        alert.Alert
+		err      error
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		bodies = nil
+		open = `{"data": []}`
+		listings = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			if r.Method == http.MethodGet {
+				listings = append(listings, r)
+				_, _ = w.Write([]byte(open))
+				return
+			}
+			var body map[string]interface{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).Should(Succeed())
+			requests = append(requests, r)
+			bodies = append(bodies, body)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		cfg = alert.OpsgenieConfig{
+			URL:        server.URL,
+			APIKeyFile: writeTempFile("opsgenie-key", []byte("K3Y\n")),
+			Responders: []alert.OpsgenieResponder{
+				{Type: "team", Name: "platform"},
+				{Type: "user", Name: "oncall@example.com"},
+			},
+			Tags:    []string{"certificates"},
+			Cluster: "prod",
+		}
+		a = alert.Alert{
+			Level:      alert.Error,
+			Message:    "certificate expired",
+			ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:     "host",
+			When:       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+			Expiration: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano(),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		notifier, err = alert.NewOpsgenieNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+		err = notifier.Send(a)
+	})
+
+	When("alert is an error", func() {
+		It("should create an alert", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(requests).Should(HaveLen(1))
+			Expect(requests[0].URL.Path).Should(Equal("/v2/alerts"))
+			Expect(requests[0].Header.Get("Authorization")).Should(Equal("GenieKey K3Y"))
+			data, _ := json.Marshal(bodies[0])
+			Expect(data).Should(MatchJSON(`{
+				"message": "ns/cert: certificate expired",
+				"alias": "cert-monitor/prod/ns/cert",
+				"description": "ns/cert: certificate expired, expired 24h0m0s ago (2021-12-31T00:00:00Z)",
+				"responders": [
+					{"type": "team", "name": "platform"},
+					{"type": "user", "username": "oncall@example.com"}
+				],
+				"tags": ["cert-monitor", "certificates"],
+				"details": {
+					"cluster": "prod",
+					"namespace": "ns",
+					"name": "cert",
+					"expiry": "2021-12-31T00:00:00Z",
+					"remaining": "expired 24h0m0s ago"
+				},
+				"entity": "ns/cert",
+				"source": "host",
+				"priority": "P2"
+			}`))
+		})
+	})
+
+	When("alert is a warning", func() {
+		BeforeEach(func() {
+			a.Level = alert.Warn
+		})
+		It("should create a lower priority alert", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(bodies).Should(HaveLen(1))
+			Expect(bodies[0]).Should(HaveKeyWithValue("priority", "P3"))
+		})
+	})
+
+	When("certificate is valid again", func() {
+		BeforeEach(func() {
+			a.Level = alert.Info
+			a.Message = "certificate is valid"
+			open = `{"data": [{"alias": "cert-monitor/prod/ns/cert"}, {"alias": "other"}]}`
+		})
+		It("should close the alert by alias", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(requests).Should(HaveLen(1))
+			Expect(requests[0].URL.EscapedPath()).Should(Equal("/v2/alerts/cert-monitor%2Fprod%2Fns%2Fcert/close"))
+			Expect(requests[0].URL.Query().Get("identifierType")).Should(Equal("alias"))
+			Expect(bodies[0]).Should(Equal(map[string]interface{}{
+				"source": "host",
+				"note":   "certificate is valid",
+			}))
+		})
+		It("should list the open alerts only once", func() {
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(listings).Should(HaveLen(1))
+			Expect(listings[0].URL.Path).Should(Equal("/v2/alerts"))
+			Expect(listings[0].URL.Query().Get("query")).Should(Equal("status:open AND tag:cert-monitor"))
+			Expect(listings[0].Header.Get("Authorization")).Should(Equal("GenieKey K3Y"))
+		})
+
+		When("certificate was not alerting", func() {
+			BeforeEach(func() {
+				open = `{"data": []}`
+			})
+			It("should not close any alert", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(requests).Should(BeEmpty())
+			})
+		})
+	})
+
+	When("Opsgenie rejects the request", func() {
+		BeforeEach(func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"Key format is not valid!"}`))
+			})
+		})
+		It("should return an error", func() {
+			Expect(err).Should(MatchError(
+				`failed to send Opsgenie request: unexpected status 401: {"message":"Key format is not valid!"}`))
+		})
+	})
+})
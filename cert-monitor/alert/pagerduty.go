@@ -0,0 +1,234 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPagerDutyURL is the URL of the PagerDuty Events API v2
+	DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	// DefaultPagerDutyAPIURL is the URL of the PagerDuty REST API
+	DefaultPagerDutyAPIURL = "https://api.pagerduty.com"
+	// pagerDutyPageSize is the number of incidents listed per request
+	pagerDutyPageSize = 100
+)
+
+// NewPagerDutyNotifier returns a Notifier that triggers a PagerDuty incident for the alerts having at least the trigger
+// level, and resolves it when an alert below the trigger level reports that the certificate is not alerting anymore.
+// The incidents are deduplicated by certificate. Only the open incidents of the service, listed once with the REST API,
+// are resolved.
+func NewPagerDutyNotifier(cfg PagerDutyConfig) (Notifier, error) {
+	routingKey, err := ioutil.ReadFile(cfg.RoutingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PagerDuty routing key: %w", err)
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PagerDuty client: %w", err)
+	}
+	apiToken, err := ioutil.ReadFile(cfg.APITokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PagerDuty API token: %w", err)
+	}
+	// the REST API authenticates with the API token only, the bearer token or basic auth of the Events API would
+	// override it
+	apiClientCfg := cfg.HTTPClientConfig
+	apiClientCfg.BearerTokenFile = ""
+	apiClientCfg.BasicAuth = nil
+	apiClient, err := newHTTPClient(apiClientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PagerDuty client: %w", err)
+	}
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = DefaultPagerDutyAPIURL
+	}
+	url := cfg.URL
+	if url == "" {
+		url = DefaultPagerDutyURL
+	}
+	triggerLevel := cfg.TriggerLevel
+	if triggerLevel == Unknown {
+		triggerLevel = Error
+	}
+	p := &pagerDutyNotifier{
+		cfg:          cfg,
+		url:          url,
+		routingKey:   strings.TrimSpace(string(routingKey)),
+		triggerLevel: triggerLevel,
+		client:       client,
+		apiURL:       apiURL,
+		apiToken:     strings.TrimSpace(string(apiToken)),
+		apiClient:    apiClient,
+	}
+	p.open = &openIncidents{list: p.listOpenIncidents}
+	return p, nil
+}
+
+type pagerDutyNotifier struct {
+	cfg          PagerDutyConfig
+	url          string
+	routingKey   string
+	triggerLevel Level
+	client       *httpClient
+
+	apiURL    string
+	apiToken  string
+	apiClient *httpClient
+	open      *openIncidents
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyIncidents struct {
+	Incidents []struct {
+		IncidentKey string `json:"incident_key"`
+	} `json:"incidents"`
+	More bool `json:"more"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp"`
+	Component     string            `json:"component"`
+	Group         string            `json:"group"`
+	Class         string            `json:"class"`
+	CustomDetails map[string]string `json:"custom_details"`
+}
+
+// Send implements Notifier contract, the alerts below the trigger level resolve the incident of the certificate when it
+// is open, the alerts of unknown level are ignored
+func (p *pagerDutyNotifier) Send(alert Alert) error {
+	event := pagerDutyEvent{
+		RoutingKey: p.routingKey,
+		DedupKey:   incidentKey(p.cfg.Cluster, alert),
+	}
+	switch {
+	case alert.Level == Unknown:
+		return nil
+	case alert.Level < p.triggerLevel:
+		open, err := p.open.isOpen(event.DedupKey)
+		if err != nil {
+			return fmt.Errorf("failed to resolve PagerDuty incident %s: %w", event.DedupKey, err)
+		}
+		if !open {
+			return nil
+		}
+		event.EventAction = "resolve"
+	default:
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:   fmt.Sprintf("%s/%s: %s", alert.ObjectRef.Namespace, alert.ObjectRef.Name, alert.Message),
+			Source:    alert.Source,
+			Severity:  pagerDutySeverity(alert.Level),
+			Timestamp: time.Unix(0, alert.When).UTC().Format(time.RFC3339),
+			Component: alert.ObjectRef.Name,
+			Group:     alert.ObjectRef.Namespace,
+			Class:     "certificate-expiration",
+			CustomDetails: map[string]string{
+				"cluster":   p.cfg.Cluster,
+				"namespace": alert.ObjectRef.Namespace,
+				"name":      alert.ObjectRef.Name,
+				"expiry":    expiry(alert),
+				"remaining": remaining(alert),
+			},
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal PagerDuty event: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create PagerDuty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := p.client.do(req); err != nil {
+		return fmt.Errorf("failed to send PagerDuty event: %w", err)
+	}
+	return nil
+}
+
+// Close implements Notifier contract
+func (p *pagerDutyNotifier) Close() error {
+	return nil
+}
+
+// listOpenIncidents returns the keys of the triggered and acknowledged incidents of the service
+func (p *pagerDutyNotifier) listOpenIncidents() (map[string]struct{}, error) {
+	keys := map[string]struct{}{}
+	for offset := 0; ; offset += pagerDutyPageSize {
+		query := url.Values{
+			"service_ids[]": {p.cfg.ServiceID},
+			"statuses[]":    {"triggered", "acknowledged"},
+			"limit":         {fmt.Sprint(pagerDutyPageSize)},
+			"offset":        {fmt.Sprint(offset)},
+		}
+		req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(p.apiURL, "/")+"/incidents?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PagerDuty request: %w", err)
+		}
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+		req.Header.Set("Authorization", "Token token="+p.apiToken)
+		body, err := p.apiClient.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list PagerDuty incidents: %w", err)
+		}
+		var page pagerDutyIncidents
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal PagerDuty incidents: %w", err)
+		}
+		for _, incident := range page.Incidents {
+			keys[incident.IncidentKey] = struct{}{}
+		}
+		if !page.More || len(page.Incidents) == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// pagerDutySeverity maps the level of the alert to a PagerDuty severity
+func pagerDutySeverity(level Level) string {
+	switch level {
+	case Error:
+		return "critical"
+	case Warn:
+		return "warning"
+	default:
+		return "info"
+	}
+}
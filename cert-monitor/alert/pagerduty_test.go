@@ -0,0 +1,222 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PagerDuty", func() {
+	var (
+		server *httptest.Server
+		events []map[string]interface{}
+		// incidents contains the pages of open incidents returned by the REST API
+		incidents []string
+		listings  []*http.Request
+
+		cfg      alert.PagerDutyConfig
+		notifier alert.Notifier
+		a        alert.Alert
+		err      error
+	)
+
+	BeforeEach(func() {
+		events = nil
+		incidents = []string{`{"incidents": [], "more": false}`}
+		listings = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			if r.URL.Path == "/incidents" {
+				listings = append(listings, r)
+				_, _ = w.Write([]byte(incidents[len(listings)-1]))
+				return
+			}
+			var event map[string]interface{}
+			Expect(json.NewDecoder(r.Body).Decode(&event)).Should(Succeed())
+			events = append(events, event)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		routingKeyFile := writeTempFile("routing-key", []byte("R0UT1NG\n"))
+		cfg = alert.PagerDutyConfig{
+			URL:            server.URL + "/v2/enqueue",
+			RoutingKeyFile: routingKeyFile,
+			Cluster:        "prod",
+			APIURL:         server.URL,
+			APITokenFile:   writeTempFile("pagerduty-token", []byte("T0K3N\n")),
+			ServiceID:      "PSERVICE",
+		}
+		a = alert.Alert{
+			Level:      alert.Error,
+			Message:    "certificate expired",
+			ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:     "host",
+			When:       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+			Expiration: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano(),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		notifier, err = alert.NewPagerDutyNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+		err = notifier.Send(a)
+	})
+
+	When("alert is an error", func() {
+		It("should trigger an incident", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(events).Should(HaveLen(1))
+			data, _ := json.Marshal(events[0])
+			Expect(data).Should(MatchJSON(`{
+				"routing_key": "R0UT1NG",
+				"event_action": "trigger",
+				"dedup_key": "cert-monitor/prod/ns/cert",
+				"payload": {
+					"summary": "ns/cert: certificate expired",
+					"source": "host",
+					"severity": "critical",
+					"timestamp": "2022-01-01T00:00:00Z",
+					"component": "cert",
+					"group": "ns",
+					"class": "certificate-expiration",
+					"custom_details": {
+						"cluster": "prod",
+						"namespace": "ns",
+						"name": "cert",
+						"expiry": "2021-12-31T00:00:00Z",
+						"remaining": "expired 24h0m0s ago"
+					}
+				}
+			}`))
+		})
+	})
+
+	When("alert is a warning", func() {
+		BeforeEach(func() {
+			a.Level = alert.Warn
+		})
+		It("should not trigger an incident", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(events).Should(BeEmpty())
+		})
+
+		When("certificate went from ERROR back to WARN", func() {
+			BeforeEach(func() {
+				incidents = []string{`{"incidents": [{"incident_key": "cert-monitor/prod/ns/cert"}], "more": false}`}
+			})
+			It("should resolve the open incident", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(events).Should(HaveLen(1))
+				Expect(events[0]).Should(HaveKeyWithValue("event_action", "resolve"))
+				Expect(events[0]).Should(HaveKeyWithValue("dedup_key", "cert-monitor/prod/ns/cert"))
+			})
+		})
+
+		When("trigger level is WARN", func() {
+			BeforeEach(func() {
+				cfg.TriggerLevel = alert.Warn
+			})
+			It("should trigger a warning incident", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(events).Should(HaveLen(1))
+				Expect(events[0]["event_action"]).Should(Equal("trigger"))
+				Expect(events[0]["payload"]).Should(HaveKeyWithValue("severity", "warning"))
+			})
+		})
+	})
+
+	When("certificate is valid again", func() {
+		BeforeEach(func() {
+			a.Level = alert.Info
+			incidents = []string{
+				`{"incidents": [{"incident_key": "cert-monitor/prod/ns/other"}], "more": true}`,
+				`{"incidents": [{"incident_key": "cert-monitor/prod/ns/cert"}], "more": false}`,
+			}
+		})
+		It("should resolve the open incident", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(events).Should(HaveLen(1))
+			Expect(events[0]).Should(Equal(map[string]interface{}{
+				"routing_key":  "R0UT1NG",
+				"event_action": "resolve",
+				"dedup_key":    "cert-monitor/prod/ns/cert",
+			}))
+		})
+		It("should list the open incidents of the service", func() {
+			Expect(listings).Should(HaveLen(2))
+			Expect(listings[0].Header.Get("Authorization")).Should(Equal("Token token=T0K3N"))
+			query := listings[1].URL.Query()
+			Expect(query["service_ids[]"]).Should(Equal([]string{"PSERVICE"}))
+			Expect(query["statuses[]"]).Should(Equal([]string{"triggered", "acknowledged"}))
+			Expect(query.Get("offset")).Should(Equal("100"))
+		})
+		It("should list the open incidents only once", func() {
+			a.ObjectRef.Name = "other"
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(listings).Should(HaveLen(2))
+			Expect(events).Should(HaveLen(2))
+		})
+
+		When("certificate was not alerting", func() {
+			BeforeEach(func() {
+				incidents = []string{`{"incidents": [], "more": false}`}
+			})
+			It("should not resolve any incident", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(events).Should(BeEmpty())
+			})
+		})
+
+		When("Events API authenticates with a bearer token", func() {
+			BeforeEach(func() {
+				cfg.BearerTokenFile = writeTempFile("bearer-token", []byte("B3AR3R\n"))
+			})
+			It("should only send the API token to the REST API", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(listings).Should(HaveLen(2))
+				Expect(listings[0].Header.Get("Authorization")).Should(Equal("Token token=T0K3N"))
+				Expect(events).Should(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("NewPagerDutyNotifier", func() {
+		It("should fail when the routing key cannot be read", func() {
+			_, err := alert.NewPagerDutyNotifier(alert.PagerDutyConfig{RoutingKeyFile: "/does/not/exist"})
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should fail when the API token cannot be read", func() {
+			cfg.APITokenFile = "/does/not/exist"
+			_, err := alert.NewPagerDutyNotifier(cfg)
+			Expect(err).Should(MatchError(HavePrefix("failed to read PagerDuty API token: ")))
+		})
+	})
+})
@@ -0,0 +1,211 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	// DefaultPinotTable is the OFFLINE table created by pinot bootstrap where the alerts are ingested when none is
+	// configured
+	DefaultPinotTable = "certsAlerts_OFFLINE"
+	// DefaultPinotBatchSize is the number of buffered alerts triggering an ingestion when none is configured
+	DefaultPinotBatchSize = 1000
+	// pinotBatchConfig is the batch configuration of the ingestion, the alerts are uploaded as JSON lines
+	pinotBatchConfig = `{"inputFormat":"json"}`
+)
+
+// NewPinotNotifier returns an AsyncNotifier that buffers the alerts and ingests them in a Pinot table with the
+// ingestFromFile API of the controller. The buffer is ingested when it reaches the batch size, when the flush interval
+// has elapsed since the first buffered alert, and when the notifier is flushed or closed. The alerts are flattened to
+// match the columns of the certsAlerts schema, e.g. objectRef.name.
+func NewPinotNotifier(cfg PinotConfig) (AsyncNotifier, error) {
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pinot client: %w", err)
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultPinotTable
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultPinotBatchSize
+	}
+	query := url.Values{
+		"tableNameWithType": {cfg.Table},
+		"batchConfigMapStr": {pinotBatchConfig},
+	}
+	idle := make(chan struct{})
+	close(idle)
+	return &pinotNotifier{
+		cfg:    cfg,
+		url:    strings.TrimSuffix(cfg.ControllerURL, "/") + "/ingestFromFile?" + query.Encode(),
+		client: client,
+		idle:   idle,
+	}, nil
+}
+
+type pinotNotifier struct {
+	cfg    PinotConfig
+	url    string
+	client *httpClient
+
+	mu     sync.Mutex
+	buffer []Alert
+	// timer ingests the buffer once the flush interval has elapsed
+	timer *time.Timer
+	// failures contains the failures of the ingestions triggered by the timer since the last flush
+	failures []error
+	// uploading is the number of ingestions triggered by the timer in progress
+	uploading int
+	// idle is closed when no ingestion triggered by the timer is in progress
+	idle chan struct{}
+}
+
+// Send implements Notifier contract, the buffer is ingested synchronously when it reaches the batch size
+func (p *pinotNotifier) Send(alert Alert) error {
+	p.mu.Lock()
+	p.buffer = append(p.buffer, alert)
+	if len(p.buffer) == 1 && p.cfg.FlushInterval > 0 {
+		p.timer = time.AfterFunc(p.cfg.FlushInterval, p.flushInBackground)
+	}
+	var batch []Alert
+	if len(p.buffer) >= p.cfg.BatchSize {
+		batch = p.take()
+	}
+	p.mu.Unlock()
+	return p.ingest(context.Background(), batch)
+}
+
+// Flush implements AsyncNotifier contract, it ingests the buffer, waits for the background ingestions in progress and
+// reports their failures
+func (p *pinotNotifier) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	batch := p.take()
+	idle := p.idle
+	p.mu.Unlock()
+	err := p.ingest(ctx, batch)
+
+	// a finished ingestion is reported even when the context is already done
+	select {
+	case <-idle:
+	default:
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return multierr.Append(err, fmt.Errorf("failed to wait for Pinot ingestion: %w", ctx.Err()))
+		}
+	}
+	p.mu.Lock()
+	failures := p.failures
+	p.failures = nil
+	p.mu.Unlock()
+	return multierr.Append(multierr.Combine(failures...), err)
+}
+
+// Close implements Notifier contract, the buffered alerts are ingested
+func (p *pinotNotifier) Close() error {
+	return p.Flush(context.Background())
+}
+
+// take returns the buffered alerts and empties the buffer, the lock must be held
+func (p *pinotNotifier) take() []Alert {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	batch := p.buffer
+	p.buffer = nil
+	return batch
+}
+
+// flushInBackground ingests the buffer when the flush interval has elapsed, the ingestion is tracked so the flush
+// waits for it
+func (p *pinotNotifier) flushInBackground() {
+	p.mu.Lock()
+	batch := p.take()
+	if len(batch) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	if p.uploading == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.uploading++
+	p.mu.Unlock()
+
+	err := p.ingest(context.Background(), batch)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failures = append(p.failures, err)
+	}
+	p.uploading--
+	if p.uploading == 0 {
+		close(p.idle)
+	}
+}
+
+// ingest uploads the alerts as a JSON lines file
+func (p *pinotNotifier) ingest(ctx context.Context, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "alerts.json")
+	if err != nil {
+		return fmt.Errorf("failed to create Pinot ingestion file: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, alert := range alerts {
+		record, err := flattenAlert(alert)
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode alert: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("failed to create Pinot ingestion file: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create Pinot request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if _, err := p.client.do(req); err != nil {
+		return fmt.Errorf("failed to ingest %d alerts in %s: %w", len(alerts), p.cfg.Table, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pinot", func() {
+	var (
+		server *httptest.Server
+		status int
+		// received and release block the ingestions on the server when set
+		received chan struct{}
+		release  chan struct{}
+
+		mu      sync.Mutex
+		queries []map[string][]string
+		batches [][]map[string]interface{}
+
+		cfg      alert.PinotConfig
+		notifier alert.AsyncNotifier
+
+		a = alert.Alert{
+			Level:      alert.Error,
+			Message:    "certificate expired",
+			ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:     "host",
+			When:       1641000000000000001,
+			Expiration: 1640000000000000001,
+		}
+	)
+
+	ingested := func() [][]map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		queries = nil
+		batches = nil
+		received = nil
+		release = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			if release != nil {
+				select {
+				case received <- struct{}{}:
+				default:
+				}
+				<-release
+			}
+			Expect(r.Method).Should(Equal(http.MethodPost))
+			Expect(r.URL.Path).Should(Equal("/ingestFromFile"))
+			file, header, err := r.FormFile("file")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(header.Filename).Should(Equal("alerts.json"))
+			var records []map[string]interface{}
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var record map[string]interface{}
+				decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+				decoder.UseNumber()
+				Expect(decoder.Decode(&record)).Should(Succeed())
+				records = append(records, record)
+			}
+			mu.Lock()
+			queries = append(queries, r.URL.Query())
+			batches = append(batches, records)
+			mu.Unlock()
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"status":"done"}`))
+		}))
+		cfg = alert.PinotConfig{
+			ControllerURL: server.URL,
+			BatchSize:     2,
+		}
+	})
+
+	AfterEach(func() {
+		if release != nil {
+			select {
+			case <-release:
+			default:
+				close(release)
+			}
+		}
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		notifier, err = alert.NewPinotNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should ingest the flattened alerts when the batch is full", func() {
+		Expect(notifier.Send(a)).Should(Succeed())
+		Expect(ingested()).Should(BeEmpty())
+		Expect(notifier.Send(a)).Should(Succeed())
+		Expect(ingested()).Should(HaveLen(1))
+		Expect(queries[0]).Should(Equal(map[string][]string{
+			"tableNameWithType": {"certsAlerts_OFFLINE"},
+			"batchConfigMapStr": {`{"inputFormat":"json"}`},
+		}))
+		Expect(batches[0]).Should(HaveLen(2))
+		Expect(batches[0][0]).Should(Equal(map[string]interface{}{
+			"level":               "ERROR",
+			"message":             "certificate expired",
+			"objectRef.name":      "cert",
+			"objectRef.namespace": "ns",
+			"source":              "host",
+			"when":                json.Number("1641000000000000001"),
+			"expiration":          json.Number("1640000000000000001"),
+		}))
+	})
+
+	It("should ingest the buffered alerts on flush", func() {
+		Expect(notifier.Send(a)).Should(Succeed())
+		Expect(notifier.Flush(context.Background())).Should(Succeed())
+		Expect(ingested()).Should(HaveLen(1))
+		Expect(notifier.Flush(context.Background())).Should(Succeed())
+		Expect(notifier.Close()).Should(Succeed())
+		Expect(ingested()).Should(HaveLen(1))
+	})
+
+	When("flush interval is set", func() {
+		BeforeEach(func() {
+			cfg.FlushInterval = 10 * time.Millisecond
+		})
+		It("should ingest the buffered alerts in background", func() {
+			Expect(notifier.Send(a)).Should(Succeed())
+			Eventually(ingested).Should(HaveLen(1))
+		})
+
+		When("background ingestion is in progress", func() {
+			BeforeEach(func() {
+				status = http.StatusInternalServerError
+				received = make(chan struct{}, 1)
+				release = make(chan struct{})
+			})
+
+			It("should wait for it on close and report its failure", func() {
+				Expect(notifier.Send(a)).Should(Succeed())
+				Eventually(received).Should(Receive())
+				closed := make(chan error, 1)
+				go func() {
+					closed <- notifier.Close()
+				}()
+				Consistently(closed, 50*time.Millisecond).ShouldNot(Receive())
+				close(release)
+				Eventually(closed).Should(Receive(MatchError(
+					`failed to ingest 1 alerts in certsAlerts_OFFLINE: unexpected status 500: {"status":"done"}`)))
+			})
+
+			It("should stop waiting when the context is done", func() {
+				Expect(notifier.Send(a)).Should(Succeed())
+				Eventually(received).Should(Receive())
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				Expect(notifier.Flush(ctx)).Should(MatchError("failed to wait for Pinot ingestion: context canceled"))
+				close(release)
+				Expect(notifier.Close()).Should(MatchError(ContainSubstring("unexpected status 500")))
+			})
+		})
+	})
+
+	When("controller rejects the ingestion", func() {
+		BeforeEach(func() {
+			status = http.StatusInternalServerError
+		})
+		It("should return an error", func() {
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(notifier.Flush(context.Background())).Should(MatchError(
+				`failed to ingest 1 alerts in certsAlerts_OFFLINE: unexpected status 500: {"status":"done"}`))
+		})
+	})
+})
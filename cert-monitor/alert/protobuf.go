@@ -0,0 +1,161 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtobufSchema is the Protobuf definition of the alerts
+const ProtobufSchema = `syntax = "proto3";
+
+package certmonitor;
+
+message Alert {
+  enum Level {
+    UNKNOWN = 0;
+    INFO = 1;
+    WARN = 2;
+    ERROR = 3;
+  }
+  message ObjectRef {
+    string name = 1;
+    string namespace = 2;
+    string kind = 3;
+    string apiVersion = 4;
+    string uid = 5;
+  }
+  message CertificateMetadata {
+    string issuerName = 1;
+    string issuerKind = 2;
+    string secretName = 3;
+    repeated string dnsNames = 4;
+    string serialNumber = 5;
+    string fingerprintSha256 = 6;
+    string keyAlgorithm = 7;
+    int64 keySize = 8;
+    int64 notBefore = 9;
+    int64 notAfter = 10;
+  }
+  Level level = 1;
+  string message = 2;
+  ObjectRef objectRef = 3;
+  string source = 4;
+  int64 when = 5;
+  int64 expiration = 6;
+  int64 remainingSeconds = 7;
+  CertificateMetadata certificate = 8;
+  map<string, string> labels = 9;
+  map<string, string> annotations = 10;
+}
+`
+
+// NewProtobufEncoder returns an Encoder that encodes the alerts in Protobuf as defined by ProtobufSchema
+func NewProtobufEncoder() Encoder {
+	return protobufEncoder{}
+}
+
+type protobufEncoder struct{}
+
+// Encode implements Encoder contract
+func (protobufEncoder) Encode(alert Alert) ([]byte, error) {
+	var data []byte
+	level := alert.Level
+	if level > Error {
+		level = Unknown
+	}
+	if level != Unknown {
+		data = protowire.AppendTag(data, 1, protowire.VarintType)
+		data = protowire.AppendVarint(data, uint64(level))
+	}
+	data = appendProtobufString(data, 2, alert.Message)
+
+	var objectRef []byte
+	objectRef = appendProtobufString(objectRef, 1, alert.ObjectRef.Name)
+	objectRef = appendProtobufString(objectRef, 2, alert.ObjectRef.Namespace)
+	objectRef = appendProtobufString(objectRef, 3, alert.ObjectRef.Kind)
+	objectRef = appendProtobufString(objectRef, 4, alert.ObjectRef.APIVersion)
+	objectRef = appendProtobufString(objectRef, 5, alert.ObjectRef.UID)
+	data = protowire.AppendTag(data, 3, protowire.BytesType)
+	data = protowire.AppendBytes(data, objectRef)
+
+	data = appendProtobufString(data, 4, alert.Source)
+	data = appendProtobufInt64(data, 5, alert.When)
+	data = appendProtobufInt64(data, 6, alert.Expiration)
+	data = appendProtobufInt64(data, 7, alert.RemainingSeconds)
+
+	if cert := alert.Certificate; cert != nil {
+		var metadata []byte
+		metadata = appendProtobufString(metadata, 1, cert.IssuerName)
+		metadata = appendProtobufString(metadata, 2, cert.IssuerKind)
+		metadata = appendProtobufString(metadata, 3, cert.SecretName)
+		for _, name := range cert.DNSNames {
+			metadata = protowire.AppendTag(metadata, 4, protowire.BytesType)
+			metadata = protowire.AppendString(metadata, name)
+		}
+		metadata = appendProtobufString(metadata, 5, cert.SerialNumber)
+		metadata = appendProtobufString(metadata, 6, cert.FingerprintSHA256)
+		metadata = appendProtobufString(metadata, 7, cert.KeyAlgorithm)
+		metadata = appendProtobufInt64(metadata, 8, int64(cert.KeySize))
+		metadata = appendProtobufInt64(metadata, 9, cert.NotBefore)
+		metadata = appendProtobufInt64(metadata, 10, cert.NotAfter)
+		data = protowire.AppendTag(data, 8, protowire.BytesType)
+		data = protowire.AppendBytes(data, metadata)
+	}
+	data = appendProtobufMap(data, 9, alert.Labels)
+	data = appendProtobufMap(data, 10, alert.Annotations)
+	return data, nil
+}
+
+// ContentType implements Encoder contract
+func (protobufEncoder) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// appendProtobufString appends the string field unless it is empty, which is its default value
+func appendProtobufString(data []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return data
+	}
+	data = protowire.AppendTag(data, num, protowire.BytesType)
+	return protowire.AppendString(data, s)
+}
+
+// appendProtobufInt64 appends the int64 field unless it is 0, which is its default value
+func appendProtobufInt64(data []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return data
+	}
+	data = protowire.AppendTag(data, num, protowire.VarintType)
+	return protowire.AppendVarint(data, uint64(v))
+}
+
+// appendProtobufMap appends the entries of the map sorted by key, each entry is a message with the key as field 1 and
+// the value as field 2
+func appendProtobufMap(data []byte, num protowire.Number, values map[string]string) []byte {
+	for _, k := range sortedKeys(values) {
+		var entry []byte
+		entry = appendProtobufString(entry, 1, k)
+		entry = appendProtobufString(entry, 2, values[k])
+		data = protowire.AppendTag(data, num, protowire.BytesType)
+		data = protowire.AppendBytes(data, entry)
+	}
+	return data
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var _ = Describe("Protobuf", func() {
+
+	Describe("Encode", func() {
+		It("should encode all the fields", func() {
+			data, err := alert.NewProtobufEncoder().Encode(alert.Alert{
+				Level:   alert.Error,
+				Message: "certificate expired",
+				ObjectRef: alert.ObjectRef{
+					Name:       "cert",
+					Namespace:  "ns",
+					Kind:       "Certificate",
+					APIVersion: "cert-manager.io/v1",
+					UID:        "uid",
+				},
+				Source:     "host",
+				When:       42,
+				Expiration: 24,
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			fields := decodeProtobuf(data)
+			Expect(fields).Should(HaveLen(6))
+			Expect(fields[1]).Should(Equal(uint64(3)))
+			Expect(fields[2]).Should(Equal("certificate expired"))
+			objectRef := decodeProtobuf([]byte(fields[3].(string)))
+			Expect(objectRef[1]).Should(Equal("cert"))
+			Expect(objectRef[2]).Should(Equal("ns"))
+			Expect(objectRef[3]).Should(Equal("Certificate"))
+			Expect(objectRef[4]).Should(Equal("cert-manager.io/v1"))
+			Expect(objectRef[5]).Should(Equal("uid"))
+			Expect(fields[4]).Should(Equal("host"))
+			Expect(fields[5]).Should(Equal(uint64(42)))
+			Expect(fields[6]).Should(Equal(uint64(24)))
+		})
+
+		It("should encode the certificate metadata", func() {
+			data, err := alert.NewProtobufEncoder().Encode(alert.Alert{
+				Level:            alert.Warn,
+				RemainingSeconds: 3600,
+				Certificate: &alert.CertificateMetadata{
+					IssuerName:        "ca",
+					IssuerKind:        "ClusterIssuer",
+					SecretName:        "cert-tls",
+					DNSNames:          []string{"example.com"},
+					SerialNumber:      "0a",
+					FingerprintSHA256: "ff",
+					KeyAlgorithm:      "ECDSA",
+					KeySize:           256,
+					NotBefore:         1,
+					NotAfter:          2,
+				},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			fields := decodeProtobuf(data)
+			Expect(fields[7]).Should(Equal(uint64(3600)))
+			Expect(decodeProtobuf([]byte(fields[8].(string)))).Should(Equal(map[protowire.Number]interface{}{
+				1:  "ca",
+				2:  "ClusterIssuer",
+				3:  "cert-tls",
+				4:  "example.com",
+				5:  "0a",
+				6:  "ff",
+				7:  "ECDSA",
+				8:  uint64(256),
+				9:  uint64(1),
+				10: uint64(2),
+			}))
+		})
+
+		It("should encode the labels and annotations as map entries", func() {
+			data, err := alert.NewProtobufEncoder().Encode(alert.Alert{
+				Level:       alert.Warn,
+				Labels:      map[string]string{"team": "a"},
+				Annotations: map[string]string{"url": "b"},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			fields := decodeProtobuf(data)
+			Expect(decodeProtobuf([]byte(fields[9].(string)))).Should(Equal(map[protowire.Number]interface{}{1: "team", 2: "a"}))
+			Expect(decodeProtobuf([]byte(fields[10].(string)))).Should(Equal(map[protowire.Number]interface{}{1: "url", 2: "b"}))
+		})
+
+		It("should omit default values", func() {
+			data, err := alert.NewProtobufEncoder().Encode(alert.Alert{
+				Level:     alert.Level(42),
+				ObjectRef: alert.ObjectRef{Name: "cert"},
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			fields := decodeProtobuf(data)
+			Expect(fields).Should(HaveLen(1))
+			Expect(decodeProtobuf([]byte(fields[3].(string)))).Should(Equal(map[protowire.Number]interface{}{1: "cert"}))
+		})
+	})
+})
+
+// decodeProtobuf decodes the varint and bytes fields of a protobuf message
+func decodeProtobuf(data []byte) map[protowire.Number]interface{} {
+	fields := map[protowire.Number]interface{}{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		Expect(n).Should(BeNumerically(">", 0))
+		data = data[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			Expect(n).Should(BeNumerically(">", 0))
+			fields[num] = v
+			data = data[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			Expect(n).Should(BeNumerically(">", 0))
+			fields[num] = string(v)
+			data = data[n:]
+		default:
+			Fail("unexpected wire type")
+		}
+	}
+	return fields
+}
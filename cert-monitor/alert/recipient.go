@@ -0,0 +1,64 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"strings"
+)
+
+// DefaultOwnerAnnotation is the annotation of the certificates listing the email addresses of their owners
+const DefaultOwnerAnnotation = "cert-monitor.io/owner"
+
+// RecipientResolver returns the email addresses notified about an alert
+type RecipientResolver interface {
+	// Recipients returns the email addresses of the recipients of the alert
+	Recipients(alert Alert) ([]string, error)
+}
+
+// NewRecipientResolver returns a RecipientResolver using the comma separated addresses of the owner annotation of the
+// alert, copied from the certificate under its LabelName, then the addresses configured for its namespace and finally
+// the default recipients
+func NewRecipientResolver(cfg EmailConfig) RecipientResolver {
+	if cfg.OwnerAnnotation == "" {
+		cfg.OwnerAnnotation = DefaultOwnerAnnotation
+	}
+	return &recipientResolver{cfg: cfg}
+}
+
+type recipientResolver struct {
+	cfg EmailConfig
+}
+
+// Recipients implements RecipientResolver contract
+func (r *recipientResolver) Recipients(alert Alert) ([]string, error) {
+	var owners []string
+	for _, owner := range strings.Split(alert.Annotations[LabelName(r.cfg.OwnerAnnotation)], ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) > 0 {
+		return owners, nil
+	}
+	if recipients, ok := r.cfg.Namespaces[alert.ObjectRef.Namespace]; ok {
+		return recipients, nil
+	}
+	return r.cfg.DefaultRecipients, nil
+}
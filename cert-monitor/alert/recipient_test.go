@@ -0,0 +1,76 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipientResolver", func() {
+	var (
+		resolver alert.RecipientResolver
+
+		cfg = alert.EmailConfig{
+			Namespaces:        map[string][]string{"ns": {"team@example.com"}},
+			DefaultRecipients: []string{"admin@example.com"},
+		}
+		a alert.Alert
+	)
+
+	BeforeEach(func() {
+		resolver = alert.NewRecipientResolver(cfg)
+		a = alert.Alert{ObjectRef: alert.ObjectRef{Name: "cert", Namespace: "ns"}}
+	})
+
+	When("alert has the owner annotation", func() {
+		BeforeEach(func() {
+			a.Annotations = map[string]string{"cert-monitor_io_owner": "alice@example.com, bob@example.com"}
+		})
+		It("should return the owners", func() {
+			Expect(resolver.Recipients(a)).Should(Equal([]string{"alice@example.com", "bob@example.com"}))
+		})
+	})
+
+	When("owner annotation is configured", func() {
+		BeforeEach(func() {
+			resolver = alert.NewRecipientResolver(alert.EmailConfig{OwnerAnnotation: "example.com/owner"})
+			a.Annotations = map[string]string{"cert-monitor_io_owner": "alice@example.com", "example_com_owner": "bob@example.com"}
+		})
+		It("should return the owners of the configured annotation", func() {
+			Expect(resolver.Recipients(a)).Should(Equal([]string{"bob@example.com"}))
+		})
+	})
+
+	When("alert has no owner annotation", func() {
+		BeforeEach(func() {
+			a.Annotations = map[string]string{"cert-monitor_io_owner": " , "}
+		})
+		It("should return the recipients of the namespace", func() {
+			Expect(resolver.Recipients(a)).Should(Equal([]string{"team@example.com"}))
+		})
+		It("should fall back to the default recipients", func() {
+			a.ObjectRef.Namespace = "other"
+			Expect(resolver.Recipients(a)).Should(Equal([]string{"admin@example.com"}))
+		})
+	})
+})
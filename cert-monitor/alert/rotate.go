@@ -0,0 +1,205 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatedTimestamp is the format of the timestamp inserted in the name of the rotated files
+const rotatedTimestamp = "2006-01-02T15-04-05.000000000"
+
+// rotatingFile is a file renamed with a timestamp once it exceeds the maximum size or age, a new file is then created.
+// The rotated files are optionally compressed with gzip and only the most recent ones are kept.
+type rotatingFile struct {
+	cfg JSONLinesConfig
+
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func newRotatingFile(cfg JSONLinesConfig) (*rotatingFile, error) {
+	r := &rotatingFile{cfg: cfg}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write implements io.Writer contract, the file is rotated before the write when it would exceed the maximum size or
+// when it is older than the maximum age
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	maxSize := int64(r.cfg.MaxSize) * 1024 * 1024
+	tooLarge := maxSize > 0 && r.size+int64(len(p)) > maxSize
+	tooOld := r.cfg.MaxAge > 0 && time.Since(r.opened) >= r.cfg.MaxAge
+	// an empty file is never rotated
+	if r.size > 0 && (tooLarge || tooOld) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer contract
+func (r *rotatingFile) Close() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", r.cfg.Path, err)
+	}
+	return nil
+}
+
+// open opens the file in append mode. The age of a new file is counted from its opening, the age of an existing one
+// from its last rotation, found in the name of the most recent rotated file, or from its last modification when it
+// was never rotated.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.cfg.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat %s: %w", r.cfg.Path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	r.opened = time.Now()
+	if r.size > 0 {
+		r.opened = info.ModTime()
+		rotated, err := r.rotatedFiles()
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		if len(rotated) > 0 {
+			r.opened, _ = r.rotationTime(rotated[len(rotated)-1])
+		}
+	}
+	return nil
+}
+
+// rotate renames the current file, opens a new one then compresses and prunes the rotated files
+func (r *rotatingFile) rotate() error {
+	if err := r.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(r.cfg.Path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.cfg.Path, ext), time.Now().UTC().Format(rotatedTimestamp), ext)
+	if err := os.Rename(r.cfg.Path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", r.cfg.Path, err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.cfg.Compress {
+		if err := compress(rotated); err != nil {
+			return err
+		}
+	}
+	return r.prune()
+}
+
+// prune removes the oldest rotated files beyond the maximum number of backups
+func (r *rotatingFile) prune() error {
+	if r.cfg.MaxBackups <= 0 {
+		return nil
+	}
+	rotated, err := r.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	if len(rotated) <= r.cfg.MaxBackups {
+		return nil
+	}
+	for _, path := range rotated[:len(rotated)-r.cfg.MaxBackups] {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove rotated file: %w", err)
+		}
+	}
+	return nil
+}
+
+// rotatedFiles returns the rotated files sorted by rotation time, the other files sharing their prefix are ignored
+func (r *rotatingFile) rotatedFiles() ([]string, error) {
+	ext := filepath.Ext(r.cfg.Path)
+	paths, err := filepath.Glob(strings.TrimSuffix(r.cfg.Path, ext) + "-*" + ext + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rotated files: %w", err)
+	}
+	var rotated []string
+	for _, path := range paths {
+		if _, ok := r.rotationTime(path); ok {
+			rotated = append(rotated, path)
+		}
+	}
+	// the timestamp format sorts the files by rotation time
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// rotationTime returns the time of the rotation from the name of the rotated file, false when the name does not match
+// the one given by rotate
+func (r *rotatingFile) rotationTime(path string) (time.Time, bool) {
+	ext := filepath.Ext(r.cfg.Path)
+	prefix := strings.TrimSuffix(r.cfg.Path, ext) + "-"
+	name := strings.TrimSuffix(path, ".gz")
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return time.Time{}, false
+	}
+	at, err := time.Parse(rotatedTimestamp, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+	return at, err == nil
+}
+
+// compress replaces the file by its gzip compressed version
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open rotated file: %w", err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed file: %w", err)
+	}
+	w := gzip.NewWriter(dst)
+	if _, err := io.Copy(w, src); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("failed to compress rotated file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("failed to compress rotated file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to compress rotated file: %w", err)
+	}
+	return os.Remove(path)
+}
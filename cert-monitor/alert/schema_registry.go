@@ -0,0 +1,117 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// SchemaTypeAvro is the type of Avro schemas in the schema registry
+	SchemaTypeAvro = "AVRO"
+
+	schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+)
+
+// ErrSchemaNotFound is returned when the schema is not registered for the subject
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// SchemaRegistryClient resolves schema IDs with a Confluent Schema Registry
+type SchemaRegistryClient interface {
+	// LookupSchema returns the ID of the schema registered under the subject, ErrSchemaNotFound is returned when the
+	// schema is not registered
+	LookupSchema(subject, schemaType, schema string) (int, error)
+	// RegisterSchema registers the schema under the subject and returns its ID
+	RegisterSchema(subject, schemaType, schema string) (int, error)
+}
+
+// NewSchemaRegistryClient returns a SchemaRegistryClient calling the REST API of the configured schema registry
+func NewSchemaRegistryClient(cfg SchemaRegistryConfig) SchemaRegistryClient {
+	return &schemaRegistryClient{
+		baseURL: strings.TrimSuffix(cfg.URL, "/"),
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+type schemaRegistryClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+type schemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type schemaResponse struct {
+	ID int `json:"id"`
+}
+
+type schemaRegistryError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// LookupSchema implements SchemaRegistryClient contract
+func (s *schemaRegistryClient) LookupSchema(subject, schemaType, schema string) (int, error) {
+	return s.post("/subjects/"+url.PathEscape(subject), schemaType, schema)
+}
+
+// RegisterSchema implements SchemaRegistryClient contract
+func (s *schemaRegistryClient) RegisterSchema(subject, schemaType, schema string) (int, error) {
+	return s.post("/subjects/"+url.PathEscape(subject)+"/versions", schemaType, schema)
+}
+
+func (s *schemaRegistryClient) post(path, schemaType, schema string) (int, error) {
+	body, err := json.Marshal(schemaRequest{
+		Schema:     schema,
+		SchemaType: schemaType,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal schema request: %w", err)
+	}
+	resp, err := s.client.Post(s.baseURL+path, schemaRegistryContentType, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to call schema registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, ErrSchemaNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var regErr schemaRegistryError
+		_ = json.NewDecoder(resp.Body).Decode(&regErr)
+		return 0, fmt.Errorf("schema registry returned status %d: %s", resp.StatusCode, regErr.Message)
+	}
+	var schemaResp schemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&schemaResp); err != nil {
+		return 0, fmt.Errorf("failed to decode schema registry response: %w", err)
+	}
+	return schemaResp.ID, nil
+}
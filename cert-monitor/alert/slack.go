@@ -0,0 +1,169 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// NewSlackNotifier returns a BatchNotifier that posts the alerts to a Slack incoming webhook. The alerts of a batch are
+// aggregated in a single digest message per channel when their number exceeds the digest threshold.
+func NewSlackNotifier(cfg SlackConfig) (BatchNotifier, error) {
+	formatter, err := newChatFormatter(cfg.ChatConfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create slack client: %w", err)
+	}
+	return &slackNotifier{
+		cfg:       cfg,
+		formatter: formatter,
+		client:    client,
+	}, nil
+}
+
+type slackNotifier struct {
+	cfg       SlackConfig
+	formatter *chatFormatter
+	client    *httpClient
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func markdown(text string) slackText {
+	return slackText{Type: "mrkdwn", Text: text}
+}
+
+// Send implements Notifier contract
+func (s *slackNotifier) Send(alert Alert) error {
+	msg, err := s.formatter.alert(alert)
+	if err != nil {
+		return err
+	}
+	return s.post(s.channel(alert), msg)
+}
+
+// SendBatch implements BatchNotifier contract
+func (s *slackNotifier) SendBatch(alerts []Alert) error {
+	var (
+		channels  []string
+		byChannel = map[string][]Alert{}
+	)
+	for _, alert := range alerts {
+		channel := s.channel(alert)
+		if _, ok := byChannel[channel]; !ok {
+			channels = append(channels, channel)
+		}
+		byChannel[channel] = append(byChannel[channel], alert)
+	}
+
+	for _, channel := range channels {
+		channel := channel
+		if err := s.formatter.send(byChannel[channel], func(msg chatMessage) error {
+			return s.post(channel, msg)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Notifier contract
+func (s *slackNotifier) Close() error {
+	return nil
+}
+
+// channel returns the channel of the first route matching the namespace of the alert, the default channel otherwise
+func (s *slackNotifier) channel(alert Alert) string {
+	for _, route := range s.cfg.Routes {
+		if ok, _ := path.Match(route.Namespace, alert.ObjectRef.Namespace); ok {
+			return route.Channel
+		}
+	}
+	return s.cfg.Channel
+}
+
+// message renders the chat message with the Slack markup
+func (s *slackNotifier) message(channel string, msg chatMessage) slackMessage {
+	var blocks []slackBlock
+	if msg.Digest() {
+		lines := make([]string, 0, len(msg.Lines)+1)
+		for _, line := range msg.Lines {
+			lines = append(lines, fmt.Sprintf("• *%s* %s", line.Level, line.Text))
+		}
+		if msg.More > 0 {
+			lines = append(lines, fmt.Sprintf("… and %d more", msg.More))
+		}
+		blocks = []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + msg.Title + "*"}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}},
+		}
+	} else {
+		fields := make([]slackText, 0, len(msg.Facts))
+		for _, fact := range msg.Facts {
+			fields = append(fields, markdown(fmt.Sprintf("*%s*\n%s", fact.Name, fact.Value)))
+		}
+		blocks = []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s* %s", msg.Level, msg.Title)}},
+			{Type: "section", Fields: fields},
+		}
+	}
+	if msg.URL != "" {
+		blocks = append(blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("<%s|Open dashboard>", msg.URL)},
+		})
+	}
+	return slackMessage{
+		Channel: channel,
+		Text:    msg.Text(),
+		Attachments: []slackAttachment{
+			{Color: levelColor(msg.Level), Blocks: blocks},
+		},
+	}
+}
+
+func (s *slackNotifier) post(channel string, msg chatMessage) error {
+	return postChatMessage(s.client, s.cfg.WebhookURL, "slack", s.message(channel, msg))
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Slack", func() {
+	var (
+		server   *httptest.Server
+		status   int
+		messages []map[string]interface{}
+
+		cfg      alert.SlackConfig
+		notifier alert.BatchNotifier
+		err      error
+
+		now     = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		expired = alert.Alert{
+			Level:      alert.Error,
+			Message:    "certificate expired",
+			ObjectRef:  alert.ObjectRef{Name: "cert1", Namespace: "team-a"},
+			Source:     "host",
+			When:       now.UnixNano(),
+			Expiration: now.Add(-2 * time.Hour).UnixNano(),
+		}
+		expiring = alert.Alert{
+			Level:      alert.Warn,
+			Message:    "certificate is about to expire",
+			ObjectRef:  alert.ObjectRef{Name: "cert2", Namespace: "team-b"},
+			Source:     "host",
+			When:       now.UnixNano(),
+			Expiration: now.Add(90 * time.Minute).UnixNano(),
+		}
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		messages = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			var msg map[string]interface{}
+			Expect(json.NewDecoder(r.Body).Decode(&msg)).Should(Succeed())
+			messages = append(messages, msg)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("invalid_payload"))
+		}))
+		cfg = alert.SlackConfig{
+			ChatConfig: alert.ChatConfig{WebhookURL: server.URL},
+			Channel:    "#alerts",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		notifier, err = alert.NewSlackNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	Describe("Send", func() {
+		BeforeEach(func() {
+			cfg.DashboardURL = "https://pinot.example.com/certs?ns={{ .ObjectRef.Namespace | urlquery }}"
+		})
+
+		JustBeforeEach(func() {
+			err = notifier.Send(expired)
+		})
+
+		It("should post a formatted message", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(messages).Should(HaveLen(1))
+			data, _ := json.Marshal(messages[0])
+			Expect(data).Should(MatchJSON(`{
+				"channel": "#alerts",
+				"text": "ERROR team-a/cert1: certificate expired",
+				"attachments": [{
+					"color": "#a30200",
+					"blocks": [
+						{"type": "section", "text": {"type": "mrkdwn", "text": "*ERROR* team-a/cert1: certificate expired"}},
+						{"type": "section", "fields": [
+							{"type": "mrkdwn", "text": "*Namespace*\nteam-a"},
+							{"type": "mrkdwn", "text": "*Name*\ncert1"},
+							{"type": "mrkdwn", "text": "*Expiry*\n2021-12-31T22:00:00Z"},
+							{"type": "mrkdwn", "text": "*Time remaining*\nexpired 2h0m0s ago"}
+						]},
+						{"type": "section", "text": {"type": "mrkdwn", "text": "<https://pinot.example.com/certs?ns=team-a|Open dashboard>"}}
+					]
+				}]
+			}`))
+		})
+
+		When("slack rejects the message", func() {
+			BeforeEach(func() {
+				status = http.StatusBadRequest
+			})
+			It("should return an error", func() {
+				Expect(err).Should(MatchError("failed to post slack message: unexpected status 400: invalid_payload"))
+			})
+		})
+	})
+
+	Describe("SendBatch", func() {
+		BeforeEach(func() {
+			cfg.Routes = []alert.SlackRoute{
+				{Namespace: "team-b*", Channel: "#team-b"},
+			}
+		})
+
+		JustBeforeEach(func() {
+			err = notifier.SendBatch([]alert.Alert{expired, expiring, expired})
+		})
+
+		When("number of alerts is below the digest threshold", func() {
+			BeforeEach(func() {
+				cfg.DigestThreshold = 2
+			})
+			It("should post one message per alert to the routed channel", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(messages).Should(HaveLen(3))
+				var channels []interface{}
+				for _, msg := range messages {
+					channels = append(channels, msg["channel"])
+				}
+				Expect(channels).Should(Equal([]interface{}{"#alerts", "#alerts", "#team-b"}))
+				Expect(messages[2]["attachments"]).Should(ContainElement(HaveKeyWithValue("color", "#daa038")))
+			})
+		})
+
+		When("number of alerts exceeds the digest threshold", func() {
+			BeforeEach(func() {
+				cfg.DigestThreshold = 1
+			})
+			It("should aggregate the alerts of a channel in a digest", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(messages).Should(HaveLen(2))
+				Expect(messages[0]["channel"]).Should(Equal("#alerts"))
+				Expect(messages[0]["text"]).Should(Equal("2 certificates need attention: 2 expired, 0 about to expire"))
+				Expect(messages[1]["channel"]).Should(Equal("#team-b"))
+				Expect(messages[1]["text"]).Should(Equal("WARN team-b/cert2: certificate is about to expire"))
+			})
+		})
+	})
+})
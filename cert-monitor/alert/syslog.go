@@ -0,0 +1,320 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SyslogNetworkJournald is the network writing the alerts to journald with its native protocol
+	SyslogNetworkJournald = "journald"
+	// DefaultJournaldSocket is the socket of journald
+	DefaultJournaldSocket = "/run/systemd/journal/socket"
+	// DefaultSyslogAppName is the app name of the syslog messages when none is configured
+	DefaultSyslogAppName = "cert-monitor"
+	// DefaultSyslogSDID is the ID of the structured data element of the syslog messages when none is configured, 32473
+	// is the private enterprise number reserved for documentation
+	DefaultSyslogSDID = "certificate@32473"
+	// DefaultSyslogTimeout is the timeout of the connection and the writes when none is configured
+	DefaultSyslogTimeout = 10 * time.Second
+	// syslogTimestamp is the RFC 5424 timestamp format, with at most 6 fractional digits
+	syslogTimestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+	// JournaldMessageIDExpired is the journald MESSAGE_ID of the ERROR alerts of the expired certificates
+	JournaldMessageIDExpired = "3b8377415cbb44598ccf133fca3af93b"
+	// JournaldMessageIDExpiring is the journald MESSAGE_ID of the WARN alerts of the certificates close to expiration
+	JournaldMessageIDExpiring = "c6ff1467052b4fd4b0f1302dba64fbb6"
+	// JournaldMessageIDValid is the journald MESSAGE_ID of the INFO alerts of the valid certificates
+	JournaldMessageIDValid = "7b67b8330c06459099f8a2ea0722a9da"
+)
+
+// syslogFacilities maps the facility names to their code
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// NewSyslogNotifier returns a Notifier that writes the alerts as RFC 5424 syslog messages over UDP, TCP, TLS or a unix
+// socket, the namespace, name and level of the certificate are part of the structured data. The journald network
+// writes the alerts to journald with its native protocol instead.
+func NewSyslogNotifier(cfg SyslogConfig) (Notifier, error) {
+	facility := syslogFacilities["daemon"]
+	if cfg.Facility != "" {
+		var ok bool
+		if facility, ok = syslogFacilities[cfg.Facility]; !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+		}
+	}
+	switch cfg.Network {
+	case "udp", "tcp", "tls", "unix", "unixgram":
+	case SyslogNetworkJournald:
+		if cfg.Address == "" {
+			cfg.Address = DefaultJournaldSocket
+		}
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", cfg.Network)
+	}
+	var tlsConfig *tls.Config
+	if cfg.Network == "tls" {
+		tlsConfig = &tls.Config{}
+		if cfg.TLS != nil {
+			var err error
+			if tlsConfig, err = newTLSConfig(*cfg.TLS); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = DefaultSyslogAppName
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = DefaultSyslogSDID
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultSyslogTimeout
+	}
+	return &syslogNotifier{
+		cfg:       cfg,
+		facility:  facility,
+		tlsConfig: tlsConfig,
+		pid:       os.Getpid(),
+	}, nil
+}
+
+type syslogNotifier struct {
+	cfg       SyslogConfig
+	facility  int
+	tlsConfig *tls.Config
+	pid       int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Send implements Notifier contract, the connection is opened again once when the write fails
+func (s *syslogNotifier) Send(alert Alert) error {
+	var msg []byte
+	if s.cfg.Network == SyslogNetworkJournald {
+		msg = s.journaldMessage(alert)
+	} else {
+		msg = s.syslogMessage(alert)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.write(msg)
+	if err != nil {
+		s.closeConn()
+		err = s.write(msg)
+	}
+	if err != nil {
+		s.closeConn()
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	return nil
+}
+
+// Close implements Notifier contract
+func (s *syslogNotifier) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeConn()
+}
+
+// write writes the message, the stream connections use the octet counting framing of RFC 6587
+func (s *syslogNotifier) write(msg []byte) error {
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		return err
+	}
+	if s.stream() {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+func (s *syslogNotifier) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	switch s.cfg.Network {
+	case "tls":
+		return tls.DialWithDialer(dialer, "tcp", s.cfg.Address, s.tlsConfig)
+	case SyslogNetworkJournald:
+		return dialer.Dial("unixgram", s.cfg.Address)
+	default:
+		return dialer.Dial(s.cfg.Network, s.cfg.Address)
+	}
+}
+
+func (s *syslogNotifier) stream() bool {
+	switch s.cfg.Network {
+	case "tcp", "tls", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *syslogNotifier) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	if err != nil {
+		return fmt.Errorf("failed to close syslog connection: %w", err)
+	}
+	return nil
+}
+
+// syslogMessage formats the alert as a RFC 5424 message
+func (s *syslogNotifier) syslogMessage(alert Alert) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s [%s",
+		s.facility*8+syslogSeverity(alert.Level),
+		time.Unix(0, alert.When).UTC().Format(syslogTimestamp),
+		syslogHeader(alert.Source),
+		syslogHeader(s.cfg.AppName),
+		s.pid,
+		syslogMsgID(alert.Level),
+		s.cfg.SDID)
+	for _, param := range s.params(alert) {
+		fmt.Fprintf(&buf, ` %s="%s"`, param[0], syslogParamEscaper.Replace(param[1]))
+	}
+	fmt.Fprintf(&buf, "] %s/%s: %s, expiry: %s",
+		alert.ObjectRef.Namespace, alert.ObjectRef.Name, alert.Message, expiry(alert))
+	return buf.Bytes()
+}
+
+// journaldMessage formats the alert with the journald native protocol
+func (s *syslogNotifier) journaldMessage(alert Alert) []byte {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", fmt.Sprintf("%s/%s: %s, expiry: %s",
+		alert.ObjectRef.Namespace, alert.ObjectRef.Name, alert.Message, expiry(alert)))
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(alert.Level)))
+	writeJournaldField(&buf, "SYSLOG_FACILITY", strconv.Itoa(s.facility))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", s.cfg.AppName)
+	if messageID := journaldMessageID(alert.Level); messageID != "" {
+		writeJournaldField(&buf, "MESSAGE_ID", messageID)
+	}
+	for _, param := range s.params(alert) {
+		writeJournaldField(&buf, "CERTIFICATE_"+strings.ToUpper(param[0]), param[1])
+	}
+	return buf.Bytes()
+}
+
+// writeJournaldField writes the field with the journald native protocol, the value containing a new line is written
+// in the binary form: the name followed by a new line, the value size as a 64-bit little endian integer and the value
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// params returns the structured data parameters of the alert
+func (s *syslogNotifier) params(alert Alert) [][2]string {
+	params := [][2]string{
+		{"namespace", alert.ObjectRef.Namespace},
+		{"name", alert.ObjectRef.Name},
+		{"level", alert.Level.String()},
+		{"expiry", expiry(alert)},
+	}
+	if s.cfg.Cluster != "" {
+		params = append(params, [2]string{"cluster", s.cfg.Cluster})
+	}
+	return params
+}
+
+// syslogParamEscaper escapes the characters not allowed in the structured data parameter values
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogHeader returns the header field or the nil value when it is empty
+func syslogHeader(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(value, " ", "_")
+}
+
+// syslogSeverity maps the level of the alert to a syslog severity
+func syslogSeverity(level Level) int {
+	switch level {
+	case Error:
+		return 3
+	case Warn:
+		return 4
+	case Info:
+		return 6
+	default:
+		return 5
+	}
+}
+
+// journaldMessageID returns the 128-bit MESSAGE_ID of the level of the alert in hexadecimal, empty when the level is
+// unknown
+func journaldMessageID(level Level) string {
+	switch level {
+	case Error:
+		return JournaldMessageIDExpired
+	case Warn:
+		return JournaldMessageIDExpiring
+	case Info:
+		return JournaldMessageIDValid
+	default:
+		return ""
+	}
+}
+
+// syslogMsgID returns the MSGID of the level of the alert
+func syslogMsgID(level Level) string {
+	switch level {
+	case Error:
+		return ReasonCertificateExpired
+	case Warn:
+		return ReasonCertificateExpiring
+	case Info:
+		return "CertificateValid"
+	default:
+		return "-"
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Syslog", func() {
+	var (
+		cfg alert.SyslogConfig
+		a   = alert.Alert{
+			Level:      alert.Error,
+			Message:    "certificate expired",
+			ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:     "host",
+			When:       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+			Expiration: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano(),
+		}
+		expected = fmt.Sprintf(`<27>1 2022-01-01T00:00:00.000000Z host cert-monitor %d CertificateExpired `+
+			`[certificate@32473 namespace="ns" name="cert" level="ERROR" expiry="2021-12-31T00:00:00Z" cluster="prod"] `+
+			`ns/cert: certificate expired, expiry: 2021-12-31T00:00:00Z`, os.Getpid())
+	)
+
+	BeforeEach(func() {
+		cfg = alert.SyslogConfig{Cluster: "prod"}
+	})
+
+	When("network is udp", func() {
+		It("should send a RFC 5424 message per datagram", func() {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer conn.Close()
+			cfg.Network = "udp"
+			cfg.Address = conn.LocalAddr().String()
+
+			notifier, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(notifier.Close()).Should(Succeed())
+
+			buf := make([]byte, 1024)
+			Expect(conn.SetReadDeadline(time.Now().Add(time.Second))).Should(Succeed())
+			n, _, err := conn.ReadFrom(buf)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(buf[:n])).Should(Equal(expected))
+		})
+	})
+
+	When("network is tcp", func() {
+		It("should frame the messages with their length", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer listener.Close()
+			received := make(chan []string, 1)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := listener.Accept()
+				Expect(err).ShouldNot(HaveOccurred())
+				reader := bufio.NewReader(conn)
+				var messages []string
+				for {
+					length, err := reader.ReadString(' ')
+					if err != nil {
+						break
+					}
+					size, err := strconv.Atoi(strings.TrimSpace(length))
+					Expect(err).ShouldNot(HaveOccurred())
+					msg := make([]byte, size)
+					_, err = io.ReadFull(reader, msg)
+					Expect(err).ShouldNot(HaveOccurred())
+					messages = append(messages, string(msg))
+				}
+				received <- messages
+			}()
+			cfg.Network = "tcp"
+			cfg.Address = listener.Addr().String()
+
+			notifier, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(notifier.Close()).Should(Succeed())
+			Eventually(received).Should(Receive(Equal([]string{expected, expected})))
+		})
+	})
+
+	When("network is journald", func() {
+		It("should use the native protocol", func() {
+			socket := filepath.Join(tempDir, "journal.sock")
+			conn, err := net.ListenPacket("unixgram", socket)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer conn.Close()
+			cfg.Network = alert.SyslogNetworkJournald
+			cfg.Address = socket
+			cfg.Facility = "local0"
+
+			notifier, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(a)).Should(Succeed())
+			Expect(notifier.Close()).Should(Succeed())
+
+			buf := make([]byte, 1024)
+			Expect(conn.SetReadDeadline(time.Now().Add(time.Second))).Should(Succeed())
+			n, _, err := conn.ReadFrom(buf)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(buf[:n])).Should(Equal("MESSAGE=ns/cert: certificate expired, expiry: 2021-12-31T00:00:00Z\n" +
+				"PRIORITY=3\n" +
+				"SYSLOG_FACILITY=16\n" +
+				"SYSLOG_IDENTIFIER=cert-monitor\n" +
+				"MESSAGE_ID=3b8377415cbb44598ccf133fca3af93b\n" +
+				"CERTIFICATE_NAMESPACE=ns\n" +
+				"CERTIFICATE_NAME=cert\n" +
+				"CERTIFICATE_LEVEL=ERROR\n" +
+				"CERTIFICATE_EXPIRY=2021-12-31T00:00:00Z\n" +
+				"CERTIFICATE_CLUSTER=prod\n"))
+		})
+
+		It("should write the multi-line values in the binary form", func() {
+			socket := filepath.Join(tempDir, "journal-multiline.sock")
+			conn, err := net.ListenPacket("unixgram", socket)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer conn.Close()
+			cfg.Network = alert.SyslogNetworkJournald
+			cfg.Address = socket
+			multiline := a
+			multiline.Message = "certificate expired\nrenew it"
+
+			notifier, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(multiline)).Should(Succeed())
+			Expect(notifier.Close()).Should(Succeed())
+
+			buf := make([]byte, 1024)
+			Expect(conn.SetReadDeadline(time.Now().Add(time.Second))).Should(Succeed())
+			n, _, err := conn.ReadFrom(buf)
+			Expect(err).ShouldNot(HaveOccurred())
+			message := "ns/cert: certificate expired\nrenew it, expiry: 2021-12-31T00:00:00Z"
+			Expect(string(buf[:n])).Should(HavePrefix("MESSAGE\n" +
+				string([]byte{byte(len(message)), 0, 0, 0, 0, 0, 0, 0}) + message + "\n" +
+				"PRIORITY=3\n"))
+		})
+	})
+
+	When("server is unreachable", func() {
+		It("should return an error", func() {
+			cfg.Network = "unix"
+			cfg.Address = filepath.Join(tempDir, "missing.sock")
+			notifier, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notifier.Send(a)).Should(MatchError(ContainSubstring("failed to write syslog message")))
+		})
+	})
+
+	When("configuration is invalid", func() {
+		It("should reject an unknown network", func() {
+			cfg.Network = "http"
+			_, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).Should(MatchError(`unsupported syslog network "http"`))
+		})
+		It("should reject an unknown facility", func() {
+			cfg.Network = "udp"
+			cfg.Facility = "local8"
+			_, err := alert.NewSyslogNotifier(cfg)
+			Expect(err).Should(MatchError(`unknown syslog facility "local8"`))
+		})
+	})
+})
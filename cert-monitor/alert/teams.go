@@ -0,0 +1,155 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import "fmt"
+
+// NewTeamsNotifier returns a BatchNotifier that posts the alerts as Adaptive Cards to a Microsoft Teams incoming
+// webhook. The alerts of a batch are aggregated in a single digest card when their number exceeds the digest threshold.
+func NewTeamsNotifier(cfg ChatConfig) (BatchNotifier, error) {
+	formatter, err := newChatFormatter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Teams client: %w", err)
+	}
+	return &teamsNotifier{
+		cfg:       cfg,
+		formatter: formatter,
+		client:    client,
+	}, nil
+}
+
+type teamsNotifier struct {
+	cfg       ChatConfig
+	formatter *chatFormatter
+	client    *httpClient
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string            `json:"$schema"`
+	Type    string            `json:"type"`
+	Version string            `json:"version"`
+	Body    []teamsElement    `json:"body"`
+	Actions []teamsAction     `json:"actions,omitempty"`
+	MSTeams map[string]string `json:"msteams"`
+}
+
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Send implements Notifier contract
+func (t *teamsNotifier) Send(alert Alert) error {
+	msg, err := t.formatter.alert(alert)
+	if err != nil {
+		return err
+	}
+	return t.post(msg)
+}
+
+// SendBatch implements BatchNotifier contract
+func (t *teamsNotifier) SendBatch(alerts []Alert) error {
+	return t.formatter.send(alerts, t.post)
+}
+
+// Close implements Notifier contract
+func (t *teamsNotifier) Close() error {
+	return nil
+}
+
+// message renders the chat message as an Adaptive Card
+func (t *teamsNotifier) message(msg chatMessage) teamsMessage {
+	body := []teamsElement{
+		{Type: "TextBlock", Text: msg.Text(), Weight: "Bolder", Size: "Medium", Color: teamsColor(msg.Level), Wrap: true},
+	}
+	if msg.Digest() {
+		body = append(body, teamsElement{Type: "TextBlock", Text: markdownLines(msg), Wrap: true})
+	} else {
+		facts := make([]teamsFact, 0, len(msg.Facts))
+		for _, fact := range msg.Facts {
+			facts = append(facts, teamsFact{Title: fact.Name, Value: fact.Value})
+		}
+		body = append(body, teamsElement{Type: "FactSet", Facts: facts})
+	}
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    body,
+		MSTeams: map[string]string{"width": "Full"},
+	}
+	if msg.URL != "" {
+		card.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "Open dashboard", URL: msg.URL}}
+	}
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: card},
+		},
+	}
+}
+
+func (t *teamsNotifier) post(msg chatMessage) error {
+	return postChatMessage(t.client, t.cfg.WebhookURL, "Teams", t.message(msg))
+}
+
+// teamsColor returns the Adaptive Card color of the alert level, Adaptive Cards only support named colors
+func teamsColor(level Level) string {
+	switch level {
+	case Info:
+		return "Good"
+	case Warn:
+		return "Warning"
+	case Error:
+		return "Attention"
+	default:
+		return "Default"
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
+)
+
+// Validate adds the violations of the configuration of the kafka notifier to v
+func (c KafkaConfig) Validate(v *validation.Validator) {
+	v.Required("topic", c.Topic)
+	v.Check(len(c.Brokers) > 0, "brokers", "must contain at least one broker")
+	for i, broker := range c.Brokers {
+		v.Check(broker != "", fmt.Sprintf("brokers[%d]", i), "must not be empty")
+	}
+	v.NotNegative("max_in_flight", int64(c.MaxInFlight))
+	v.OneOf("encoding", c.Encoding, "", EncodingJSON, EncodingAvro, EncodingProtobuf)
+	if c.Encoding == EncodingAvro {
+		v.Check(c.SchemaRegistry.URL != "", "schema_registry.url", "is required by the avro encoding")
+	}
+	registry := v.Section("schema_registry")
+	registry.URL("url", c.SchemaRegistry.URL)
+	registry.NotNegativeDuration("timeout", c.SchemaRegistry.Timeout)
+	v.OneOf("cloud_events", c.CloudEvents, "", CloudEventsStructured, CloudEventsBinary)
+	if !c.Format.IsZero() {
+		v.Check(c.Encoding == "" || c.Encoding == EncodingJSON, "format", "is only supported by the json encoding")
+		if _, err := NewFormattedJSONEncoder(c.Format); err != nil {
+			v.Check(false, "format", "%s", err)
+		}
+	}
+}
+
+// Validate adds the violations of the configuration of the enabled notifiers to v
+func (c NotifiersConfig) Validate(v *validation.Validator) {
+	if c.Webhook != nil {
+		c.Webhook.Validate(v.Section("webhook"))
+	}
+	if c.Slack != nil {
+		c.Slack.Validate(v.Section("slack"))
+	}
+	if c.Teams != nil {
+		c.Teams.Validate(v.Section("teams"))
+	}
+	if c.Mattermost != nil {
+		c.Mattermost.Validate(v.Section("mattermost"))
+	}
+	if c.GoogleChat != nil {
+		c.GoogleChat.Validate(v.Section("google_chat"))
+	}
+	if c.PagerDuty != nil {
+		c.PagerDuty.Validate(v.Section("pagerduty"))
+	}
+	if c.Opsgenie != nil {
+		c.Opsgenie.Validate(v.Section("opsgenie"))
+	}
+	if c.Alertmanager != nil {
+		c.Alertmanager.Validate(v.Section("alertmanager"))
+	}
+	if c.KubernetesEvents != nil {
+		v.Section("kubernetes_events").NotNegativeDuration("timeout", c.KubernetesEvents.Timeout)
+	}
+	if c.Email != nil {
+		c.Email.Validate(v.Section("email"))
+	}
+	if c.Syslog != nil {
+		c.Syslog.Validate(v.Section("syslog"))
+	}
+	if c.JSONLines != nil {
+		c.JSONLines.Validate(v.Section("json_lines"))
+	}
+	if c.Pinot != nil {
+		c.Pinot.Validate(v.Section("pinot"))
+	}
+}
+
+// Validate adds the violations of the configuration of the HTTP client to v
+func (c HTTPClientConfig) Validate(v *validation.Validator) {
+	v.NotNegativeDuration("timeout", c.Timeout)
+	if c.BasicAuth != nil {
+		v.Required("basic_auth.username", c.BasicAuth.Username)
+	}
+	if c.TLS != nil {
+		c.TLS.Validate(v.Section("tls"))
+	}
+}
+
+// Validate adds the violations of the TLS configuration to v
+func (c TLSConfig) Validate(v *validation.Validator) {
+	v.Check(c.CertFile != "" || c.KeyFile == "", "cert_file", "is required by key_file")
+	v.Check(c.KeyFile != "" || c.CertFile == "", "key_file", "is required by cert_file")
+}
+
+// Validate adds the violations of the configuration of the webhook notifier to v
+func (c WebhookConfig) Validate(v *validation.Validator) {
+	c.HTTPClientConfig.Validate(v)
+	v.Required("url", c.URL)
+	v.URL("url", c.URL)
+	validateTemplate(v, "body", c.Body)
+}
+
+// Validate adds the violations of the configuration of the chat notifier to v
+func (c ChatConfig) Validate(v *validation.Validator) {
+	c.HTTPClientConfig.Validate(v)
+	v.Required("webhook_url", c.WebhookURL)
+	v.URL("webhook_url", c.WebhookURL)
+	validateTemplate(v, "dashboard_url", c.DashboardURL)
+	v.NotNegative("digest_threshold", int64(c.DigestThreshold))
+}
+
+// Validate adds the violations of the configuration of the slack notifier to v
+func (c SlackConfig) Validate(v *validation.Validator) {
+	c.ChatConfig.Validate(v)
+	for i, route := range c.Routes {
+		r := v.Section(fmt.Sprintf("routes[%d]", i))
+		_, err := path.Match(route.Namespace, "")
+		r.Check(route.Namespace != "" && err == nil, "namespace", "must be a valid pattern, got %q", route.Namespace)
+		r.Required("channel", route.Channel)
+	}
+}
+
+// Validate adds the violations of the configuration of the PagerDuty notifier to v
+func (c PagerDutyConfig) Validate(v *validation.Validator) {
+	c.HTTPClientConfig.Validate(v)
+	v.URL("url", c.URL)
+	v.Required("routing_key_file", c.RoutingKeyFile)
+	v.URL("api_url", c.APIURL)
+	// the open incidents are listed with the REST API to resolve them
+	v.Required("api_token_file", c.APITokenFile)
+	v.Required("service_id", c.ServiceID)
+}
+
+// Validate adds the violations of the configuration of the Opsgenie notifier to v
+func (c OpsgenieConfig) Validate(v *validation.Validator) {
+	c.HTTPClientConfig.Validate(v)
+	v.URL("url", c.URL)
+	v.Required("api_key_file", c.APIKeyFile)
+	for i, responder := range c.Responders {
+		r := v.Section(fmt.Sprintf("responders[%d]", i))
+		r.OneOf("type", responder.Type, "team", "user", "escalation", "schedule")
+		r.Check(responder.Name != "" || responder.ID != "", "name", "is required when id is not set")
+	}
+}
+
+// Validate adds the violations of the configuration of the Alertmanager notifier to v
+func (c AlertmanagerConfig) Validate(v *validation.Validator) {
+	c.HTTPClientConfig.Validate(v)
+	v.Required("url", c.URL)
+	v.URL("url", c.URL)
+	v.NotNegativeDuration("resolve_timeout", c.ResolveTimeout)
+}
+
+// Validate adds the violations of the configuration of the email notifier to v
+func (c EmailConfig) Validate(v *validation.Validator) {
+	_, _, err := net.SplitHostPort(c.Address)
+	v.Check(err == nil, "address", "must be host:port, got %q", c.Address)
+	v.Required("from", c.From)
+	v.Check(c.PasswordFile == "" || c.Username != "", "username", "is required by password_file")
+	if c.TLS != nil {
+		c.TLS.Validate(v.Section("tls"))
+	}
+	v.NotNegativeDuration("timeout", c.Timeout)
+}
+
+// Validate adds the violations of the configuration of the syslog notifier to v
+func (c SyslogConfig) Validate(v *validation.Validator) {
+	v.OneOf("network", c.Network, "udp", "tcp", "tls", "unix", "unixgram", SyslogNetworkJournald)
+	v.Check(c.Address != "" || c.Network == SyslogNetworkJournald, "address", "is required")
+	if c.TLS != nil {
+		c.TLS.Validate(v.Section("tls"))
+	}
+	v.NotNegativeDuration("timeout", c.Timeout)
+	if c.Facility != "" {
+		_, found := syslogFacilities[c.Facility]
+		v.Check(found, "facility", "unknown facility %q", c.Facility)
+	}
+}
+
+// Validate adds the violations of the configuration of the JSON Lines notifier to v
+func (c JSONLinesConfig) Validate(v *validation.Validator) {
+	v.NotNegative("max_size", int64(c.MaxSize))
+	v.NotNegativeDuration("max_age", c.MaxAge)
+	v.NotNegative("max_backups", int64(c.MaxBackups))
+}
+
+// Validate adds the violations of the configuration of the Pinot notifier to v
+func (c PinotConfig) Validate(v *validation.Validator) {
+	c.HTTPClientConfig.Validate(v)
+	v.Required("controller_url", c.ControllerURL)
+	v.URL("controller_url", c.ControllerURL)
+	v.Check(c.Table == "" || strings.HasSuffix(c.Table, "_OFFLINE"), "table", "must be an OFFLINE table, got %q", c.Table)
+	v.NotNegative("batch_size", int64(c.BatchSize))
+	v.NotNegativeDuration("flush_interval", c.FlushInterval)
+}
+
+// validateTemplate checks the template of the alerts parses, it is not checked when empty
+func validateTemplate(v *validation.Validator, path, text string) {
+	if text == "" {
+		return
+	}
+	_, err := template.New(path).Funcs(templateFuncs).Parse(text)
+	v.Check(err == nil, path, "must be a valid template: %v", err)
+}
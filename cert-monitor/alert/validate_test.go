@@ -0,0 +1,123 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var v *validation.Validator
+
+	BeforeEach(func() {
+		v = validation.New()
+	})
+
+	violations := func() []string {
+		err := v.Err()
+		if err == nil {
+			return nil
+		}
+		var messages []string
+		for _, violation := range err.(*validation.Error).Violations {
+			messages = append(messages, violation.String())
+		}
+		return messages
+	}
+
+	Describe("KafkaConfig", func() {
+		It("should accept a valid configuration", func() {
+			alert.KafkaConfig{Topic: "alerts", Brokers: []string{"kafka:9092"}}.Validate(v.Section("notifier"))
+			Expect(v.Err()).ShouldNot(HaveOccurred())
+		})
+
+		It("should report all the violations with their path", func() {
+			alert.KafkaConfig{
+				Brokers:     []string{""},
+				MaxInFlight: -1,
+				Encoding:    alert.EncodingAvro,
+				CloudEvents: "envelope",
+				Format: alert.WireFormatConfig{
+					Rename: map[string]string{"source": "host"},
+					Fields: map[string]string{"host": "x"},
+				},
+			}.Validate(v.Section("notifier"))
+			Expect(violations()).Should(Equal([]string{
+				"notifier.topic: is required",
+				"notifier.brokers[0]: must not be empty",
+				"notifier.max_in_flight: must not be negative, got -1",
+				"notifier.schema_registry.url: is required by the avro encoding",
+				`notifier.cloud_events: must be one of "structured", "binary", got "envelope"`,
+				"notifier.format: is only supported by the json encoding",
+				"notifier.format: failed to create JSON encoder: static field host conflicts with the rename of source",
+			}))
+			Expect(v.Err()).Should(MatchError(HavePrefix("invalid configuration: notifier.topic: is required; ")))
+		})
+	})
+
+	Describe("NotifiersConfig", func() {
+		It("should only validate the enabled notifiers", func() {
+			alert.NotifiersConfig{}.Validate(v.Section("notifiers"))
+			Expect(v.Err()).ShouldNot(HaveOccurred())
+		})
+
+		It("should report the violations of each notifier", func() {
+			alert.NotifiersConfig{
+				Webhook: &alert.WebhookConfig{URL: "hooks.example.com", Body: "{{.Message"},
+				Slack: &alert.SlackConfig{
+					ChatConfig: alert.ChatConfig{WebhookURL: "https://hooks.slack.com/x"},
+					Routes:     []alert.SlackRoute{{Namespace: "team-*"}},
+				},
+				PagerDuty: &alert.PagerDutyConfig{RoutingKeyFile: "/key", APITokenFile: "/token"},
+				Opsgenie: &alert.OpsgenieConfig{
+					APIKeyFile: "/key",
+					Responders: []alert.OpsgenieResponder{{Type: "group", Name: "ops"}},
+				},
+				Alertmanager: &alert.AlertmanagerConfig{
+					HTTPClientConfig: alert.HTTPClientConfig{TLS: &alert.TLSConfig{KeyFile: "/key"}},
+					URL:              "http://alertmanager:9093",
+				},
+				Email:     &alert.EmailConfig{Address: "smtp", From: "monitor@example.com", Timeout: -time.Second},
+				Syslog:    &alert.SyslogConfig{Network: alert.SyslogNetworkJournald},
+				JSONLines: &alert.JSONLinesConfig{MaxBackups: -1},
+				Pinot:     &alert.PinotConfig{Table: "certsAlerts_REALTIME"},
+			}.Validate(v.Section("notifiers"))
+			Expect(violations()).Should(Equal([]string{
+				`notifiers.webhook.url: must be an absolute http or https URL, got "hooks.example.com"`,
+				`notifiers.webhook.body: must be a valid template: template: body:1: unclosed action`,
+				"notifiers.slack.routes[0].channel: is required",
+				"notifiers.pagerduty.service_id: is required",
+				`notifiers.opsgenie.responders[0].type: must be one of "team", "user", "escalation", "schedule", got "group"`,
+				"notifiers.alertmanager.tls.cert_file: is required by key_file",
+				`notifiers.email.address: must be host:port, got "smtp"`,
+				"notifiers.email.timeout: must not be negative, got -1s",
+				"notifiers.json_lines.max_backups: must not be negative, got -1",
+				"notifiers.pinot.controller_url: is required",
+				`notifiers.pinot.table: must be an OFFLINE table, got "certsAlerts_REALTIME"`,
+			}))
+		})
+	})
+})
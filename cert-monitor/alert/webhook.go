@@ -0,0 +1,98 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// DefaultWebhookBody is the template of the webhook body when none is configured, it renders the alert as JSON
+const DefaultWebhookBody = `{{ json . }}`
+
+// NewWebhookNotifier returns a Notifier that sends each alert in an HTTP request whose body is rendered by the
+// configured template
+func NewWebhookNotifier(cfg WebhookConfig) (Notifier, error) {
+	body := cfg.Body
+	if body == "" {
+		body = DefaultWebhookBody
+	}
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook body template: %w", err)
+	}
+	client, err := newHTTPClient(cfg.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook client: %w", err)
+	}
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &webhookNotifier{
+		cfg:    cfg,
+		method: method,
+		body:   tmpl,
+		client: client,
+	}, nil
+}
+
+// templateFuncs are the functions available in the templates rendering the alerts
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+type webhookNotifier struct {
+	cfg    WebhookConfig
+	method string
+	body   *template.Template
+	client *httpClient
+}
+
+// Send implements Notifier contract
+func (w *webhookNotifier) Send(alert Alert) error {
+	var body bytes.Buffer
+	if err := w.body.Execute(&body, alert); err != nil {
+		return fmt.Errorf("failed to render webhook body: %w", err)
+	}
+	req, err := http.NewRequest(w.method, w.cfg.URL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if _, err := w.client.do(req); err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	return nil
+}
+
+// Close implements Notifier contract
+func (w *webhookNotifier) Close() error {
+	return nil
+}
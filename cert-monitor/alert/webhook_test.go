@@ -0,0 +1,196 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package alert_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhook", func() {
+	var (
+		server   *httptest.Server
+		status   int
+		response string
+		request  *http.Request
+		body     string
+
+		cfg      alert.WebhookConfig
+		notifier alert.Notifier
+		err      error
+
+		a = alert.Alert{
+			Level:     alert.Warn,
+			Message:   "certificate is about to expire",
+			ObjectRef: alert.ObjectRef{Name: "cert", Namespace: "ns"},
+			Source:    "host",
+			When:      42,
+		}
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		request = r
+		body = string(data)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	})
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		response = ""
+		request = nil
+		server = httptest.NewServer(handler)
+		cfg = alert.WebhookConfig{
+			URL: server.URL + "/hook",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		notifier, err = alert.NewWebhookNotifier(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+		err = notifier.Send(a)
+	})
+
+	When("body is not configured", func() {
+		It("should POST the alert in JSON", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(request.Method).Should(Equal(http.MethodPost))
+			Expect(request.URL.Path).Should(Equal("/hook"))
+			Expect(request.Header.Get("Content-Type")).Should(Equal("application/json"))
+			Expect(body).Should(MatchJSON(`{"level":"WARN","message":"certificate is about to expire","objectRef":{"name":"cert","namespace":"ns"},"source":"host","when":42}`))
+		})
+	})
+
+	When("method, headers and body are configured", func() {
+		BeforeEach(func() {
+			cfg.Method = http.MethodPut
+			cfg.Headers = map[string]string{"Content-Type": "text/plain", "X-Team": "platform"}
+			cfg.Body = `{{ .Level }} {{ .ObjectRef.Namespace }}/{{ .ObjectRef.Name }}: {{ .Message }}`
+		})
+		It("should render the request", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(request.Method).Should(Equal(http.MethodPut))
+			Expect(request.Header.Get("Content-Type")).Should(Equal("text/plain"))
+			Expect(request.Header.Get("X-Team")).Should(Equal("platform"))
+			Expect(body).Should(Equal("WARN ns/cert: certificate is about to expire"))
+		})
+	})
+
+	When("bearer token is configured", func() {
+		BeforeEach(func() {
+			tokenFile := writeTempFile("token", []byte("s3cr3t\n"))
+			cfg.BearerTokenFile = tokenFile
+		})
+		It("should send the token", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(request.Header.Get("Authorization")).Should(Equal("Bearer s3cr3t"))
+		})
+	})
+
+	When("basic auth is configured", func() {
+		BeforeEach(func() {
+			cfg.BasicAuth = &alert.BasicAuthConfig{Username: "user", Password: "pass"}
+		})
+		It("should send the credentials", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			username, password, ok := request.BasicAuth()
+			Expect(ok).Should(BeTrue())
+			Expect(username).Should(Equal("user"))
+			Expect(password).Should(Equal("pass"))
+		})
+	})
+
+	When("server uses TLS", func() {
+		BeforeEach(func() {
+			server.Close()
+			server = httptest.NewTLSServer(handler)
+			caFile := writeTempFile("ca.pem", pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: server.Certificate().Raw,
+			}))
+			cfg.URL = server.URL
+			cfg.TLS = &alert.TLSConfig{CAFile: caFile}
+		})
+		It("should verify the server with the CA", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(request).ShouldNot(BeNil())
+		})
+	})
+
+	When("server responds with an error", func() {
+		BeforeEach(func() {
+			status = http.StatusBadRequest
+			response = strings.Repeat("x", 1000)
+		})
+		It("should return the truncated response", func() {
+			Expect(err).Should(MatchError("failed to call webhook: unexpected status 400: " + strings.Repeat("x", 512) + "..."))
+		})
+	})
+
+	When("server responds with an error in multi-byte characters", func() {
+		BeforeEach(func() {
+			status = http.StatusBadRequest
+			// the 512 bytes limit falls in the middle of the 171st character of 3 bytes
+			response = strings.Repeat("€", 300)
+		})
+		It("should truncate the response at the start of a character", func() {
+			Expect(err).Should(MatchError("failed to call webhook: unexpected status 400: " + strings.Repeat("€", 170) + "..."))
+			Expect(utf8.ValidString(err.Error())).Should(BeTrue())
+		})
+	})
+
+	When("server is too slow", func() {
+		BeforeEach(func() {
+			server.Close()
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			}))
+			cfg.URL = server.URL
+			cfg.Timeout = 10 * time.Millisecond
+		})
+		It("should time out", func() {
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("Client.Timeout exceeded"))
+		})
+	})
+
+	Describe("NewWebhookNotifier", func() {
+		It("should reject invalid templates", func() {
+			_, err := alert.NewWebhookNotifier(alert.WebhookConfig{Body: "{{ .Level "})
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(HavePrefix("failed to parse webhook body template"))
+		})
+	})
+})
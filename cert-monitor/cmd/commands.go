@@ -0,0 +1,100 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/config"
+)
+
+// command is a subcommand of cert-monitor
+type command struct {
+	// usage describes the arguments of the command
+	usage string
+	// description describes what the command does
+	description string
+	// ownConfig is set when the command reads the configuration from its arguments rather than from --config, cfg is
+	// nil
+	ownConfig bool
+	// run runs the command with its arguments, the output is written to out
+	run func(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error
+}
+
+// commands are the subcommands of cert-monitor keyed by name
+var commands = map[string]command{
+	"config": {
+		usage:       "config validate <path>...",
+		description: "validates the configuration files, or the config.yml of ConfigMaps, and prints all their violations",
+		ownConfig:   true,
+		run:         runConfigCommand,
+	},
+	"history": {
+		usage: "history [--broker <url>] [--name <certificate>] [--namespace <namespace>] [--level <levels>] " +
+			"[--since <duration> | --from <time> --to <time>] [--limit <n>] [--output table|json]",
+		description: "prints the past alerts stored in Pinot, the most recent first",
+		run:         runHistoryCommand,
+	},
+	"pinot": {
+		usage:       "pinot bootstrap [--controller <url>] [--diff]",
+		description: "creates or updates the Pinot schema and realtime table of the alerts",
+		run:         runPinotCommand,
+	},
+}
+
+// runCommand runs the command named by the first argument with the configuration loaded from --config
+func runCommand(ctx context.Context, opts cli, out io.Writer) error {
+	cmd, ok := commands[opts.args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q. Refer --help", opts.args[0])
+	}
+	var cfg *config.Config
+	if !cmd.ownConfig {
+		var err error
+		if cfg, err = newFromCLI(opts); err != nil {
+			return err
+		}
+	}
+	return cmd.run(ctx, cfg, opts.args[1:], out)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: cert-monitor --config <path> [--kubeconfig <path>] [--set <path>=<value>]... [command]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Checks the expiration of the certificates when no command is passed.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s\n    \t%s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/config"
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configMapKey is the key of the configuration in the ConfigMap mounted by the job
+const configMapKey = "config.yml"
+
+// configMap is the subset of a k8s ConfigMap holding the configuration
+type configMap struct {
+	Kind string            `yaml:"kind"`
+	Data map[string]string `yaml:"data"`
+}
+
+// runConfigCommand runs the config commands, only validate is supported. It prints the violations of each file and
+// fails when any file is invalid, so it can be used in CI before applying the ConfigMap.
+func runConfigCommand(_ context.Context, _ *config.Config, args []string, out io.Writer) error {
+	if len(args) < 2 || args[0] != "validate" {
+		return errors.New("expected config validate <path>. Refer --help")
+	}
+	paths := args[1:]
+	invalid := 0
+	for _, path := range paths {
+		err := validateConfigFile(path)
+		var validationErr *validation.Error
+		switch {
+		case err == nil:
+			fmt.Fprintf(out, "%s: valid\n", path)
+		case errors.As(err, &validationErr):
+			invalid++
+			fmt.Fprintf(out, "%s: %d violations\n", path, len(validationErr.Violations))
+			for _, violation := range validationErr.Violations {
+				fmt.Fprintf(out, "  %s\n", violation)
+			}
+		default:
+			invalid++
+			fmt.Fprintf(out, "%s: %s\n", path, err)
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d configurations are invalid", invalid, len(paths))
+	}
+	return nil
+}
+
+// validateConfigFile loads and validates the configuration file, the configuration is read from the config.yml key
+// when the file is a ConfigMap
+func validateConfigFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var cm configMap
+	if err := yaml.Unmarshal(data, &cm); err == nil && cm.Kind == "ConfigMap" {
+		content, found := cm.Data[configMapKey]
+		if !found {
+			return fmt.Errorf("ConfigMap has no %s key", configMapKey)
+		}
+		data = []byte(content)
+	}
+	// the configuration is validated as written, without the overrides of the environment
+	_, err = newFromBytes(data, nil, nil)
+	return err
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/config"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+)
+
+// runHistoryCommand prints the past alerts stored in Pinot
+func runHistoryCommand(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	flags.SetOutput(out)
+	brokerURL := flags.String("broker", cfg.Pinot.BrokerURL, "Pinot broker URL, overrides pinot.broker_url")
+	name := flags.String("name", "", "Name of the certificate")
+	namespace := flags.String("namespace", "", "Namespace of the certificate")
+	levels := flags.String("level", "", "Comma separated levels of the alerts, e.g. WARN,ERROR")
+	since := flags.Duration("since", 24*time.Hour, "Returns the alerts more recent than the duration, ignored when --from is set")
+	from := flags.String("from", "", "Beginning of the time range in RFC3339, e.g. 2022-01-01T00:00:00Z")
+	to := flags.String("to", "", "End of the time range in RFC3339")
+	limit := flags.Int("limit", pinot.DefaultHistoryLimit, "Maximum number of alerts")
+	output := flags.String("output", "table", "Output format, table or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output %q, expected table or json", *output)
+	}
+
+	query := pinot.HistoryQuery{
+		Name:      *name,
+		Namespace: *namespace,
+		From:      time.Now().Add(-*since),
+		Limit:     *limit,
+	}
+	if *levels != "" {
+		for _, s := range strings.Split(*levels, ",") {
+			level, err := alert.ParseLevel(strings.ToUpper(strings.TrimSpace(s)))
+			if err != nil {
+				return err
+			}
+			query.Levels = append(query.Levels, level)
+		}
+	}
+	var err error
+	if *from != "" {
+		if query.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("failed to parse --from: %w", err)
+		}
+	}
+	if *to != "" {
+		if query.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("failed to parse --to: %w", err)
+		}
+	}
+
+	pinotCfg := cfg.Pinot.WithDefaults()
+	pinotCfg.BrokerURL = *brokerURL
+	client, err := pinot.NewBrokerClient(pinotCfg)
+	if err != nil {
+		return err
+	}
+	alerts, err := pinot.NewAlertHistory(client, pinotCfg.Table, cfg.Notifier.Format.Rename).Alerts(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(alerts)
+	}
+	return printAlerts(out, alerts)
+}
+
+// printAlerts prints the alerts as a table
+func printAlerts(out io.Writer, alerts []alert.Alert) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WHEN\tLEVEL\tNAMESPACE\tNAME\tEXPIRATION\tMESSAGE")
+	for _, a := range alerts {
+		expiration := ""
+		if a.Expiration != 0 {
+			expiration = formatTime(a.Expiration)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatTime(a.When), a.Level, a.ObjectRef.Namespace, a.ObjectRef.Name, expiration, a.Message)
+	}
+	return w.Flush()
+}
+
+func formatTime(nanos int64) string {
+	return time.Unix(0, nanos).UTC().Format(time.RFC3339)
+}
@@ -20,23 +20,31 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
 	"github.com/dvergnes/pinot-playground/cert-monitor/config"
 	"github.com/dvergnes/pinot-playground/cert-monitor/internal/version"
 	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
 
 	"github.com/Shopify/sarama"
 	certmanager "github.com/jetstack/cert-manager/pkg/client/clientset/versioned"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -62,7 +70,22 @@ func main() {
 	suggaredLogger.Infow("starting certificate monitor", "version", version.Version)
 
 	// 1. read config
-	config, k8sCfg, err := newFromCLI(suggaredLogger)
+	opts := parseCLI()
+	// the certificates are only checked when no command is passed
+	if len(opts.args) > 0 {
+		if err := runCommand(context.Background(), opts, os.Stdout); err != nil {
+			suggaredLogger.Fatalw("command failed", "command", opts.args[0], "error", err)
+		}
+		return
+	}
+	config, err := newFromCLI(opts)
+	if err != nil {
+		suggaredLogger.Fatalw("failed to initialize application", "error", err)
+	}
+	if !config.Monitor.Recovery && (config.Notifiers.PagerDuty != nil || config.Notifiers.Opsgenie != nil) {
+		suggaredLogger.Warn("monitor.recovery is disabled, the incidents of the renewed certificates are not resolved")
+	}
+	k8sCfg, err := newK8sConfig(suggaredLogger, opts.kubeConfigPath)
 	if err != nil {
 		suggaredLogger.Fatalw("failed to initialize application", "error", err)
 	}
@@ -71,71 +94,322 @@ func main() {
 	if err != nil {
 		suggaredLogger.Fatalw("failed to create k8s client", "error", err)
 	}
+	var (
+		secrets    corev1client.SecretsGetter
+		namespaces corev1client.NamespacesGetter
+	)
+	readNamespaces := len(config.Monitor.Labels.Namespace) > 0 || len(config.Monitor.Annotations.Namespace) > 0
+	if config.Monitor.GathererConfig.ReadSecrets || readNamespaces {
+		k8sClientSet, err := kubernetes.NewForConfig(k8sCfg)
+		if err != nil {
+			suggaredLogger.Fatalw("failed to create k8s client", "error", err)
+		}
+		if config.Monitor.GathererConfig.ReadSecrets {
+			secrets = k8sClientSet.CoreV1()
+		}
+		if readNamespaces {
+			namespaces = k8sClientSet.CoreV1()
+		}
+	}
 	gatherer := monitor.NewKubernetesCertificateInfoGatherer(
 		suggaredLogger.Named("k8sCertInfoGatherer"),
 		clientSet,
+		secrets,
+		namespaces,
 		config.Monitor.GathererConfig)
 	//notifier := alert.NewLogNotifier(suggaredLogger.Named("logNotifier"))
-	producer,err := sarama.NewSyncProducer(config.Notifier.Brokers, nil)
-	if err!=nil {
-		suggaredLogger.Fatalw("failed to create kafka producer", "error", err)
+	notifier, err := newNotifier(config, k8sCfg)
+	if err != nil {
+		suggaredLogger.Fatalw("failed to create notifier", "error", err)
+	}
+	history, err := newAlertHistory(config)
+	if err != nil {
+		suggaredLogger.Fatalw("failed to create alert history", "error", err)
 	}
-	notifier := alert.NewKafkaNotifier(config.Notifier.Topic, producer)
 	certMonitor := monitor.NewCertificateMonitor(
 		suggaredLogger.Named("monitor"),
 		gatherer,
 		notifier,
+		history,
 		sysClock,
-		config.Monitor.Threshold)
-	// 3. run the monitor
-	if err := certMonitor.CheckCertificates(context.Background()); err != nil {
-		suggaredLogger.Fatalw("failed to verify certificate", "error", err)
+		config.Monitor)
+	// 3. run the monitor, the notifier is closed even when the check fails to deliver the pending alerts and events
+	checkErr := certMonitor.CheckCertificates(context.Background())
+	if err := notifier.Close(); err != nil {
+		checkErr = multierr.Append(checkErr, fmt.Errorf("failed to close notifier: %w", err))
+	}
+	if checkErr != nil {
+		suggaredLogger.Fatalw("failed to verify certificate", "error", checkErr)
+	}
+}
+
+// newNotifier returns the notifier sending the alerts to all the configured notifiers
+func newNotifier(cfg *config.Config, k8sCfg *rest.Config) (alert.Notifier, error) {
+	var notifiers []alert.Notifier
+	// the notifiers not resolving incidents only get the warnings and the errors
+	if len(cfg.Notifier.Brokers) > 0 {
+		notifier, err := newKafkaNotifier(cfg.Notifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kafka notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.Webhook != nil {
+		notifier, err := alert.NewWebhookNotifier(*cfg.Notifiers.Webhook)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create webhook notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.Slack != nil {
+		notifier, err := alert.NewSlackNotifier(*cfg.Notifiers.Slack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create slack notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.Teams != nil {
+		notifier, err := alert.NewTeamsNotifier(*cfg.Notifiers.Teams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Teams notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.Mattermost != nil {
+		notifier, err := alert.NewMattermostNotifier(*cfg.Notifiers.Mattermost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Mattermost notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.GoogleChat != nil {
+		notifier, err := alert.NewGoogleChatNotifier(*cfg.Notifiers.GoogleChat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Google Chat notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.PagerDuty != nil {
+		notifier, err := alert.NewPagerDutyNotifier(*cfg.Notifiers.PagerDuty)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PagerDuty notifier: %w", err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if cfg.Notifiers.Opsgenie != nil {
+		notifier, err := alert.NewOpsgenieNotifier(*cfg.Notifiers.Opsgenie)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Opsgenie notifier: %w", err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if cfg.Notifiers.Alertmanager != nil {
+		notifier, err := alert.NewAlertmanagerNotifier(*cfg.Notifiers.Alertmanager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Alertmanager notifier: %w", err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if cfg.Notifiers.KubernetesEvents != nil {
+		clientSet, err := kubernetes.NewForConfig(k8sCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create k8s client: %w", err)
+		}
+		recorder := alert.NewKubernetesEventRecorder(clientSet.CoreV1(), *cfg.Notifiers.KubernetesEvents)
+		notifiers = append(notifiers, alert.NewKubernetesEventsNotifier(recorder))
+	}
+	if cfg.Notifiers.Email != nil {
+		resolver := alert.NewRecipientResolver(*cfg.Notifiers.Email)
+		notifier, err := alert.NewEmailNotifier(*cfg.Notifiers.Email, resolver)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.Syslog != nil {
+		notifier, err := alert.NewSyslogNotifier(*cfg.Notifiers.Syslog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create syslog notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	if cfg.Notifiers.Pinot != nil {
+		notifier, err := alert.NewPinotNotifier(*cfg.Notifiers.Pinot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Pinot notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+	// the alerts are written to the standard output when no notifier is configured
+	if cfg.Notifiers.JSONLines != nil || len(notifiers) == 0 {
+		jsonLinesCfg := alert.JSONLinesConfig{}
+		if cfg.Notifiers.JSONLines != nil {
+			jsonLinesCfg = *cfg.Notifiers.JSONLines
+		}
+		notifier, err := alert.NewJSONLinesNotifier(jsonLinesCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JSON Lines notifier: %w", err)
+		}
+		notifiers = append(notifiers, alert.NewLevelFilterNotifier(notifier, alert.Warn))
+	}
+
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return alert.NewMultiNotifier(notifiers...), nil
+}
+
+// newAlertHistory returns the alert history used by the flapping detection, nil when the detection is disabled
+func newAlertHistory(cfg *config.Config) (*pinot.AlertHistory, error) {
+	if cfg.Monitor.Flapping.Window <= 0 {
+		return nil, nil
+	}
+	pinotCfg := cfg.Pinot.WithDefaults()
+	client, err := pinot.NewBrokerClient(pinotCfg)
+	if err != nil {
+		return nil, err
 	}
+	return pinot.NewAlertHistory(client, pinotCfg.Table, cfg.Notifier.Format.Rename), nil
+}
 
+func newKafkaNotifier(cfg alert.KafkaConfig) (alert.Notifier, error) {
+	encoder, err := alert.NewEncoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Async {
+		saramaCfg := sarama.NewConfig()
+		saramaCfg.Producer.Return.Successes = true
+		producer, err := sarama.NewAsyncProducer(cfg.Brokers, saramaCfg)
+		if err != nil {
+			return nil, err
+		}
+		return alert.NewAsyncKafkaNotifier(cfg, encoder, producer), nil
+	}
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, nil)
+	if err != nil {
+		return nil, err
+	}
+	return alert.NewKafkaNotifier(cfg, encoder, producer), nil
 }
 
-func newFromBytes(data []byte) (*config.Config, error) {
+// newFromBytes returns the configuration of the data overridden by the environment variables, formatted as
+// os.Environ, then by the path=value overrides of the --set flags. The unknown fields and the violations of the
+// configuration are rejected.
+func newFromBytes(data []byte, environ []string, overrides []string) (*config.Config, error) {
 	config := config.Config{}
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
 		return nil, fmt.Errorf("failed to unmarshal config data: %s", err)
 	}
-
-	// TODO: config validation
+	if err := config.ApplyEnv(environ); err != nil {
+		return nil, fmt.Errorf("failed to override config from environment: %w", err)
+	}
+	for _, override := range overrides {
+		i := strings.Index(override, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid override %q, expected <path>=<value>", override)
+		}
+		if err := config.Set(override[:i], override[i+1:]); err != nil {
+			return nil, fmt.Errorf("failed to override config: %w", err)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 	return &config, nil
 }
 
-func newFromFile(filepath string) (*config.Config, error) {
+func newFromFile(filepath string, environ []string, overrides []string) (*config.Config, error) {
 	data, err := ioutil.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %s", err)
 	}
-	return newFromBytes(data)
+	return newFromBytes(data, environ, overrides)
 }
 
-func newFromCLI(logger *zap.SugaredLogger) (*config.Config, *rest.Config, error) {
+// cli contains the options and the arguments of the command line
+type cli struct {
+	configPath     string
+	kubeConfigPath string
+	// overrides contains the path=value overrides of the configuration fields
+	overrides []string
+	// args contains the command, e.g. pinot, followed by its arguments
+	args []string
+}
+
+func parseCLI() cli {
 	configPath := flag.String("config", "", "Configuration file path")
 	kubeConfigPath := flag.String("kubeconfig", "", "Kubectl configuration file path")
+	var overrides stringsFlag
+	flag.Var(&overrides, "set", "Overrides a configuration field, e.g. notifier.brokers=kafka:9092. "+
+		"It takes precedence over the "+config.EnvPrefix+"* environment variables, which take precedence over the "+
+		"configuration file. It can be repeated")
+	flag.Usage = usage
 	flag.Parse()
+	return cli{
+		configPath:     *configPath,
+		kubeConfigPath: *kubeConfigPath,
+		overrides:      overrides,
+		args:           flag.Args(),
+	}
+}
+
+// stringsFlag is a flag which can be repeated, its values are collected in order
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
 
-	if *configPath == "" {
-		return nil, nil, errors.New("missing config file path. Refer --help")
+func newFromCLI(opts cli) (*config.Config, error) {
+	if opts.configPath == "" {
+		return nil, errors.New("missing config file path. Refer --help")
 	}
-	cfg, err := newFromFile(*configPath)
+	cfg, err := newFromFile(opts.configPath, os.Environ(), opts.overrides)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	// the email notifier reads the owners from the annotation copied from the certificates to the alerts
+	if cfg.Notifiers.Email != nil {
+		owner := cfg.Notifiers.Email.OwnerAnnotation
+		if owner == "" {
+			owner = alert.DefaultOwnerAnnotation
+		}
+		if !contains(cfg.Monitor.Annotations.Certificate, owner) {
+			cfg.Monitor.Annotations.Certificate = append(cfg.Monitor.Annotations.Certificate, owner)
+		}
 	}
+	return cfg, nil
+}
 
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func newK8sConfig(logger *zap.SugaredLogger, kubeConfigPath string) (*rest.Config, error) {
 	var k8sCfg *rest.Config
-	if *kubeConfigPath == "" {
+	var err error
+	if kubeConfigPath == "" {
 		logger.Info("creating k8s client using in-cluster configuration")
 		k8sCfg, err = rest.InClusterConfig()
 	} else {
-		logger.Infow("creating k8s client using external configuration", "path", *kubeConfigPath)
-		k8sCfg, err = clientcmd.BuildConfigFromFlags("", *kubeConfigPath)
+		logger.Infow("creating k8s client using external configuration", "path", kubeConfigPath)
+		k8sCfg, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath)
 	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create k8s client: %w", err)
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
 	}
-
-	return cfg, k8sCfg, nil
+	return k8sCfg, nil
 }
@@ -0,0 +1,83 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/config"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+)
+
+// runPinotCommand runs the pinot commands, only bootstrap is supported
+func runPinotCommand(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "bootstrap" {
+		return errors.New("expected pinot bootstrap. Refer --help")
+	}
+	flags := flag.NewFlagSet("pinot bootstrap", flag.ContinueOnError)
+	flags.SetOutput(out)
+	controllerURL := flags.String("controller", cfg.Pinot.ControllerURL, "Pinot controller URL, overrides pinot.controller_url")
+	dryRun := flags.Bool("diff", false, "Print the changes without applying them")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	pinotCfg := cfg.Pinot.WithDefaults()
+	pinotCfg.ControllerURL = *controllerURL
+
+	schema, err := pinot.AlertSchema(pinotCfg.Table)
+	if err != nil {
+		return err
+	}
+	schema = schema.WithMapKeys("labels", cfg.Monitor.Labels.Keys()).
+		WithMapKeys("annotations", cfg.Monitor.Annotations.Keys()).
+		WithWireFormat(cfg.Notifier.Format)
+	table, err := pinot.RealtimeTableConfig(pinotCfg, cfg.Notifier, schema)
+	if err != nil {
+		return fmt.Errorf("failed to generate table config: %w", err)
+	}
+	tables := []pinot.TableConfig{table}
+	// the pinot notifier ingests the alerts in the OFFLINE table of the same name
+	if cfg.Notifiers.Pinot != nil {
+		offline, err := pinot.OfflineTableConfig(pinotCfg, schema)
+		if err != nil {
+			return fmt.Errorf("failed to generate table config: %w", err)
+		}
+		tables = append(tables, offline)
+	}
+	controller, err := pinot.NewController(pinotCfg)
+	if err != nil {
+		return err
+	}
+	results, err := pinot.Bootstrap(ctx, controller, schema, tables, *dryRun)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		fmt.Fprintf(out, "%s %s: %s\n", result.Kind, result.Name, result.Action)
+		for _, change := range result.Changes {
+			fmt.Fprintf(out, "  %s\n", change)
+		}
+	}
+	return nil
+}
@@ -20,11 +20,46 @@
 package config
 
 import (
+	"reflect"
+
 	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
 	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
 )
 
 type Config struct {
 	Monitor monitor.Config `yaml:"monitor"`
 	Notifier alert.KafkaConfig `yaml:"notifier"`
+	// Notifiers contains the configuration of the notifiers besides kafka
+	Notifiers alert.NotifiersConfig `yaml:"notifiers"`
+	// Pinot contains the configuration to reach the Pinot cluster storing the alerts
+	Pinot pinot.Config `yaml:"pinot"`
+}
+
+// Validate returns a *validation.Error listing all the violations of the configuration with the YAML path of the
+// fields, nil when the configuration is valid
+func (c Config) Validate() error {
+	v := validation.New()
+	c.Monitor.Validate(v.Section("monitor"))
+	// the kafka notifier is enabled when its section is set
+	if !reflect.ValueOf(c.Notifier).IsZero() {
+		c.Notifier.Validate(v.Section("notifier"))
+	}
+	c.Notifiers.Validate(v.Section("notifiers"))
+	c.Pinot.Validate(v.Section("pinot"))
+	if c.Monitor.Flapping.Window > 0 {
+		v.Check(c.Pinot.BrokerURL != "", "pinot.broker_url", "is required by monitor.flapping.window")
+	}
+	if c.Notifiers.Pinot != nil {
+		// pinot bootstrap creates the OFFLINE table of pinot.table, with the schema of the kafka notifier
+		table := c.Notifiers.Pinot.Table
+		if table == "" {
+			table = alert.DefaultPinotTable
+		}
+		offline := c.Pinot.WithDefaults().Table + "_OFFLINE"
+		v.Check(table == offline, "notifiers.pinot.table", "must be %s, the OFFLINE table of pinot.table, got %q", offline, table)
+		v.Check(len(c.Notifier.Format.Rename) == 0, "notifiers.pinot", "does not support the columns renamed by notifier.format")
+	}
+	return v.Err()
 }
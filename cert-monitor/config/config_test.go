@@ -0,0 +1,64 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config_test
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/config"
+	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var cfg config.Config
+
+	BeforeEach(func() {
+		cfg = config.Config{
+			Monitor: monitor.Config{
+				GathererConfig: monitor.GathererConfig{PageSize: 100, Timeout: time.Minute},
+			},
+			Notifier: alert.KafkaConfig{Topic: "alerts", Brokers: []string{"kafka:9092"}},
+			Notifiers: alert.NotifiersConfig{
+				Pinot: &alert.PinotConfig{ControllerURL: "http://pinot-controller:9000"},
+			},
+		}
+	})
+
+	It("should accept the OFFLINE table created by the bootstrap", func() {
+		Expect(cfg.Validate()).Should(Succeed())
+	})
+
+	It("should reject an OFFLINE table not created by the bootstrap", func() {
+		cfg.Pinot = pinot.Config{Table: "alerts"}
+		Expect(cfg.Validate()).Should(MatchError(ContainSubstring(
+			`notifiers.pinot.table: must be alerts_OFFLINE, the OFFLINE table of pinot.table, got "certsAlerts_OFFLINE"`)))
+	})
+
+	It("should reject the columns renamed by the kafka notifier", func() {
+		cfg.Notifier.Format = alert.WireFormatConfig{Rename: map[string]string{"source": "host"}}
+		Expect(cfg.Validate()).Should(MatchError(ContainSubstring(
+			"notifiers.pinot: does not support the columns renamed by notifier.format")))
+	})
+})
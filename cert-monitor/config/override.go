@@ -0,0 +1,226 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix is the prefix of the environment variables overriding the configuration, the YAML path of the field
+	// follows in upper case with dots replaced by underscores, e.g. CERT_MONITOR_NOTIFIER_BROKERS
+	EnvPrefix = "CERT_MONITOR_"
+	// EnvFileSuffix is the suffix of the environment variables containing the path of the file holding the value,
+	// e.g. CERT_MONITOR_NOTIFIERS_WEBHOOK_BASIC_AUTH_PASSWORD_FILE for the secrets
+	EnvFileSuffix = "_FILE"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// EnvName returns the environment variable overriding the field at the YAML path
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// Paths returns the sorted YAML paths of the fields which can be overridden
+func Paths() []string {
+	var paths []string
+	collectPaths("", reflect.TypeOf(Config{}), &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func collectPaths(prefix string, t reflect.Type, paths *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, inline := yamlName(field)
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if isLeaf(fieldType) {
+			*paths = append(*paths, join(prefix, name))
+			continue
+		}
+		if inline {
+			collectPaths(prefix, fieldType, paths)
+		} else {
+			collectPaths(join(prefix, name), fieldType, paths)
+		}
+	}
+}
+
+// ApplyEnv overrides the fields with the environment variables, environ is formatted as os.Environ. The value of a
+// field is read from the file named by the variable with the EnvFileSuffix, setting both variables is an error. The
+// other variables with the EnvPrefix are ignored, e.g. the ones defined by k8s for a service named cert-monitor.
+func (c *Config) ApplyEnv(environ []string) error {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 && strings.HasPrefix(kv, EnvPrefix) {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	for _, path := range Paths() {
+		name := EnvName(path)
+		value, found := env[name]
+		file, fileFound := env[name+EnvFileSuffix]
+		if found && fileFound {
+			return fmt.Errorf("both %s and %s are set", name, name+EnvFileSuffix)
+		}
+		if fileFound {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", name+EnvFileSuffix, err)
+			}
+			value, found = strings.TrimRight(string(data), "\r\n"), true
+			name += EnvFileSuffix
+		}
+		if !found {
+			continue
+		}
+		if err := c.Set(path, value); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Set overrides the field at the YAML path, e.g. notifier.brokers, with the value. The strings are set as is, the
+// lists of scalars are separated by commas and the other values are parsed as YAML, e.g. {team: a} for a map. The
+// sections on the path are created when not set, so setting a field of a notifier enables it.
+func (c *Config) Set(path, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	for _, name := range strings.Split(path, ".") {
+		field, found := fieldByYAMLName(v, name)
+		if !found {
+			return fmt.Errorf("unknown configuration field %q", path)
+		}
+		v = field
+		if v.Kind() == reflect.Ptr && !isLeaf(v.Type().Elem()) {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+	if v.Kind() == reflect.Struct && !isLeaf(v.Type()) {
+		return fmt.Errorf("configuration field %q is a section", path)
+	}
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+	return nil
+}
+
+// fieldByYAMLName returns the field of the struct named name in YAML, the fields of the inline structs included
+func fieldByYAMLName(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		yamlField, inline := yamlName(v.Type().Field(i))
+		if inline {
+			if field, found := fieldByYAMLName(v.Field(i), name); found {
+				return field, true
+			}
+			continue
+		}
+		if yamlField == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Ptr {
+		target := reflect.New(v.Type().Elem())
+		if err := setValue(target.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(target)
+		return nil
+	}
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+		return nil
+	case v.Kind() == reflect.Slice && isLeaf(v.Type().Elem()) && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, strings.TrimSpace(item)); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem)
+		}
+		v.Set(items)
+		return nil
+	default:
+		target := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(value), target.Interface()); err != nil {
+			return err
+		}
+		v.Set(target.Elem())
+		return nil
+	}
+}
+
+// yamlName returns the name of the field in YAML and whether its fields are inlined in the parent
+func yamlName(field reflect.StructField) (string, bool) {
+	options := strings.Split(field.Tag.Get("yaml"), ",")
+	for _, option := range options[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+	if options[0] == "" {
+		return strings.ToLower(field.Name), false
+	}
+	return options[0], false
+}
+
+// isLeaf returns true when the type is set from a single value rather than being a section
+func isLeaf(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
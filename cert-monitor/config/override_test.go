@@ -0,0 +1,144 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package config_test
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Overrides", func() {
+	var cfg config.Config
+
+	BeforeEach(func() {
+		cfg = config.Config{
+			Notifier: alert.KafkaConfig{Topic: "alerts", Brokers: []string{"kafka:9092"}},
+		}
+	})
+
+	Describe("EnvName", func() {
+		It("should derive the variable from the YAML path", func() {
+			Expect(config.EnvName("notifier.brokers")).Should(Equal("CERT_MONITOR_NOTIFIER_BROKERS"))
+			Expect(config.EnvName("monitor.gatherer.page_size")).Should(Equal("CERT_MONITOR_MONITOR_GATHERER_PAGE_SIZE"))
+		})
+	})
+
+	Describe("Paths", func() {
+		It("should list the fields with the inline fields in their parent", func() {
+			Expect(config.Paths()).Should(ContainElements(
+				"monitor.threshold",
+				"monitor.messages.warn",
+				"notifier.brokers",
+				"notifier.schema_registry.url",
+				"notifiers.webhook.url",
+				"notifiers.webhook.basic_auth.password",
+				"pinot.broker_url",
+			))
+			Expect(config.Paths()).ShouldNot(ContainElement("notifiers.webhook"))
+		})
+	})
+
+	Describe("Set", func() {
+		It("should parse the value according to the type of the field", func() {
+			Expect(cfg.Set("notifier.brokers", "a:9092, b:9092")).Should(Succeed())
+			Expect(cfg.Set("notifier.async", "true")).Should(Succeed())
+			Expect(cfg.Set("monitor.gatherer.page_size", "50")).Should(Succeed())
+			Expect(cfg.Set("monitor.gatherer.timeout", "15s")).Should(Succeed())
+			Expect(cfg.Set("monitor.labels.static", "{team: payments}")).Should(Succeed())
+			Expect(cfg.Set("monitor.messages.warn", "{{.Name}} expires soon")).Should(Succeed())
+			Expect(cfg.Set("notifier.format.fields", "{cluster: eu-1}")).Should(Succeed())
+
+			Expect(cfg.Notifier.Brokers).Should(Equal([]string{"a:9092", "b:9092"}))
+			Expect(cfg.Notifier.Async).Should(BeTrue())
+			Expect(cfg.Monitor.GathererConfig.PageSize).Should(BeEquivalentTo(50))
+			Expect(cfg.Monitor.GathererConfig.Timeout).Should(Equal(15 * time.Second))
+			Expect(cfg.Monitor.Labels.Static).Should(Equal(map[string]string{"team": "payments"}))
+			Expect(cfg.Monitor.Messages.Warn).ShouldNot(BeNil())
+			Expect(cfg.Notifier.Format.Fields).Should(Equal(map[string]string{"cluster": "eu-1"}))
+		})
+
+		It("should create the sections on the path", func() {
+			Expect(cfg.Set("notifiers.pagerduty.trigger_level", "WARN")).Should(Succeed())
+			Expect(cfg.Set("notifiers.pagerduty.timeout", "5s")).Should(Succeed())
+			Expect(cfg.Notifiers.PagerDuty).ShouldNot(BeNil())
+			Expect(cfg.Notifiers.PagerDuty.TriggerLevel).Should(Equal(alert.Warn))
+			Expect(cfg.Notifiers.PagerDuty.Timeout).Should(Equal(5 * time.Second))
+		})
+
+		It("should reject an unknown field", func() {
+			Expect(cfg.Set("notifier.broker", "a:9092")).Should(MatchError(`unknown configuration field "notifier.broker"`))
+		})
+
+		It("should reject a section", func() {
+			Expect(cfg.Set("monitor.gatherer", "50")).Should(MatchError(`configuration field "monitor.gatherer" is a section`))
+		})
+
+		It("should reject an invalid value", func() {
+			Expect(cfg.Set("monitor.gatherer.page_size", "many")).Should(MatchError(ContainSubstring("invalid value for monitor.gatherer.page_size")))
+			Expect(cfg.Set("monitor.messages.error", "{{.Unknown}}")).Should(HaveOccurred())
+		})
+	})
+
+	Describe("ApplyEnv", func() {
+		It("should override the fields with the environment variables", func() {
+			Expect(cfg.ApplyEnv([]string{
+				"CERT_MONITOR_NOTIFIER_BROKERS=kafka-eu:9092",
+				"CERT_MONITOR_MONITOR_THRESHOLD=720h",
+				"CERT_MONITOR_SERVICE_HOST=10.0.0.1",
+				"HOME=/root",
+			})).Should(Succeed())
+			Expect(cfg.Notifier.Brokers).Should(Equal([]string{"kafka-eu:9092"}))
+			Expect(cfg.Notifier.Topic).Should(Equal("alerts"))
+			Expect(cfg.Monitor.Threshold).Should(Equal(720 * time.Hour))
+		})
+
+		When("the value is in a file", func() {
+			var path string
+
+			BeforeEach(func() {
+				path = writeTempFile("password", []byte("s3cr3t\n"))
+			})
+
+			It("should read the value from the file", func() {
+				Expect(cfg.ApplyEnv([]string{
+					"CERT_MONITOR_NOTIFIERS_WEBHOOK_BASIC_AUTH_PASSWORD_FILE=" + path,
+				})).Should(Succeed())
+				Expect(cfg.Notifiers.Webhook).ShouldNot(BeNil())
+				Expect(cfg.Notifiers.Webhook.BasicAuth.Password).Should(Equal("s3cr3t"))
+			})
+
+			It("should reject both the value and the file", func() {
+				Expect(cfg.ApplyEnv([]string{
+					"CERT_MONITOR_NOTIFIERS_WEBHOOK_BASIC_AUTH_PASSWORD=secret",
+					"CERT_MONITOR_NOTIFIERS_WEBHOOK_BASIC_AUTH_PASSWORD_FILE=" + path,
+				})).Should(MatchError(ContainSubstring("both CERT_MONITOR_NOTIFIERS_WEBHOOK_BASIC_AUTH_PASSWORD and CERT_MONITOR_NOTIFIERS_WEBHOOK_BASIC_AUTH_PASSWORD_FILE are set")))
+			})
+		})
+
+		It("should report the variable of an invalid value", func() {
+			Expect(cfg.ApplyEnv([]string{"CERT_MONITOR_NOTIFIER_ASYNC=maybe"})).Should(MatchError(ContainSubstring("failed to apply CERT_MONITOR_NOTIFIER_ASYNC")))
+		})
+	})
+})
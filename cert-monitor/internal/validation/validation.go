@@ -0,0 +1,140 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Violation describes a field of the configuration violating a constraint
+type Violation struct {
+	// Path is the YAML path of the field, e.g. monitor.gatherer.page_size
+	Path string
+	// Message describes the constraint violated by the field
+	Message string
+}
+
+// String returns the path of the field followed by the message
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Error is the error returned for an invalid configuration, it contains all the violations
+type Error struct {
+	Violations []Violation
+}
+
+// Error implements error contract
+func (e *Error) Error() string {
+	violations := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		violations = append(violations, v.String())
+	}
+	return "invalid configuration: " + strings.Join(violations, "; ")
+}
+
+// Validator collects the violations of a section of the configuration, so they are all reported at once with the YAML
+// path of the faulty fields. The paths are relative to the section.
+type Validator struct {
+	prefix     string
+	violations *[]Violation
+}
+
+// New returns a Validator of the root of the configuration
+func New() *Validator {
+	return &Validator{violations: &[]Violation{}}
+}
+
+// Section returns a Validator of a nested section, e.g. notifiers.slack or routes[0], whose violations are collected
+// along the ones of v
+func (v *Validator) Section(path string) *Validator {
+	return &Validator{prefix: v.path(path), violations: v.violations}
+}
+
+func (v *Validator) path(path string) string {
+	if v.prefix == "" || strings.HasPrefix(path, "[") {
+		return v.prefix + path
+	}
+	return v.prefix + "." + path
+}
+
+// Check adds a violation of the field at path when ok is false
+func (v *Validator) Check(ok bool, path string, format string, args ...interface{}) {
+	if !ok {
+		*v.violations = append(*v.violations, Violation{Path: v.path(path), Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// Required checks the field is set
+func (v *Validator) Required(path, value string) {
+	v.Check(value != "", path, "is required")
+}
+
+// Positive checks the duration is strictly positive
+func (v *Validator) Positive(path string, d time.Duration) {
+	v.Check(d > 0, path, "must be positive, got %s", d)
+}
+
+// NotNegative checks the number is positive or zero, zero meaning the default value is used
+func (v *Validator) NotNegative(path string, n int64) {
+	v.Check(n >= 0, path, "must not be negative, got %d", n)
+}
+
+// NotNegativeDuration checks the duration is positive or zero, zero meaning the default value is used
+func (v *Validator) NotNegativeDuration(path string, d time.Duration) {
+	v.Check(d >= 0, path, "must not be negative, got %s", d)
+}
+
+// OneOf checks the value is one of the allowed ones, the empty string is allowed when it is one of them
+func (v *Validator) OneOf(path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	quoted := make([]string, 0, len(allowed))
+	for _, a := range allowed {
+		if a != "" {
+			quoted = append(quoted, fmt.Sprintf("%q", a))
+		}
+	}
+	v.Check(false, path, "must be one of %s, got %q", strings.Join(quoted, ", "), value)
+}
+
+// URL checks the value is an absolute http or https URL, it is not checked when empty
+func (v *Validator) URL(path, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", path,
+		"must be an absolute http or https URL, got %q", value)
+}
+
+// Err returns an *Error with all the violations, nil when there is none
+func (v *Validator) Err() error {
+	if len(*v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: append([]Violation(nil), *v.violations...)}
+}
@@ -19,7 +19,14 @@
 
 package monitor
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
+)
 
 // Config contains the configuration for the monitor
 type Config struct {
@@ -27,8 +34,163 @@ type Config struct {
 	// if a certificate is valid for the next 20 days but the threshold is set to 30 days, it is considered as close to
 	// expiration.
 	Threshold time.Duration `yaml:"threshold"`
+	// Recovery enables the INFO alerts for the certificates that are valid and not close to expiration, so the
+	// notifiers tracking incidents can resolve them once the certificate is renewed
+	Recovery bool `yaml:"recovery"`
 	// GathererConfig contains the configuration for fetching the certificate info
 	GathererConfig GathererConfig `yaml:"gatherer"`
+	// Flapping contains the configuration of the flapping detection
+	Flapping FlappingConfig `yaml:"flapping"`
+	// Labels defines the labels added to the alerts
+	Labels LabelsConfig `yaml:"labels"`
+	// Annotations defines the annotations added to the alerts
+	Annotations LabelsConfig `yaml:"annotations"`
+	// Messages defines the templates of the messages of the alerts per level
+	Messages MessagesConfig `yaml:"messages"`
+}
+
+// MessagesConfig defines the templates of the messages of the alerts per level, the default message of the level is
+// used when its template is not set
+type MessagesConfig struct {
+	// Info is the template of the INFO alerts, sent for the valid certificates when the recovery is enabled
+	Info *MessageTemplate `yaml:"info"`
+	// Warn is the template of the WARN alerts, sent for the certificates close to expiration
+	Warn *MessageTemplate `yaml:"warn"`
+	// Error is the template of the ERROR alerts, sent for the expired certificates
+	Error *MessageTemplate `yaml:"error"`
+}
+
+// template returns the template of the level, nil when not set
+func (c MessagesConfig) template(level alert.Level) *MessageTemplate {
+	switch level {
+	case alert.Info:
+		return c.Info
+	case alert.Warn:
+		return c.Warn
+	case alert.Error:
+		return c.Error
+	default:
+		return nil
+	}
+}
+
+// LabelsConfig defines the labels, or the annotations, added to the alerts. The entries of the Certificate override the
+// ones of its namespace, which override the static ones. The keys are named with alert.LabelName in the alerts, e.g.
+// app.kubernetes.io/name becomes app_kubernetes_io_name.
+type LabelsConfig struct {
+	// Static defines the entries added to all the alerts, e.g. the cluster or the environment
+	Static map[string]string `yaml:"static"`
+	// Certificate is the allow-list of the keys copied from the Certificate objects
+	Certificate []string `yaml:"certificate"`
+	// Namespace is the allow-list of the keys copied from the namespaces of the certificates, e.g. team or env
+	Namespace []string `yaml:"namespace"`
+}
+
+// Keys returns the sorted names of the entries which can be added to the alerts
+func (c LabelsConfig) Keys() []string {
+	unique := map[string]struct{}{}
+	for k := range c.Static {
+		unique[alert.LabelName(k)] = struct{}{}
+	}
+	for _, k := range c.Certificate {
+		unique[alert.LabelName(k)] = struct{}{}
+	}
+	for _, k := range c.Namespace {
+		unique[alert.LabelName(k)] = struct{}{}
+	}
+	keys := make([]string, 0, len(unique))
+	for k := range unique {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validate checks the keys, they must not be empty and two keys must not have the same name in the alerts
+func (c LabelsConfig) validate(v *validation.Validator) {
+	if _, found := c.Static[""]; found {
+		v.Check(false, "static", "keys must not be empty")
+	}
+	static := make([]string, 0, len(c.Static))
+	for k := range c.Static {
+		static = append(static, k)
+	}
+	sort.Strings(static)
+	keys := labelKeys{}
+	for _, k := range static {
+		keys.validate(v, "static", k)
+	}
+	for i, k := range c.Certificate {
+		path := fmt.Sprintf("certificate[%d]", i)
+		v.Check(k != "", path, "must not be empty")
+		keys.validate(v, path, k)
+	}
+	for i, k := range c.Namespace {
+		path := fmt.Sprintf("namespace[%d]", i)
+		v.Check(k != "", path, "must not be empty")
+		keys.validate(v, path, k)
+	}
+}
+
+// labelKeys maps the names of the entries in the alerts to their key
+type labelKeys map[string]string
+
+// validate checks that no other key has the same name in the alerts, e.g. app.kubernetes.io/name and
+// app_kubernetes_io_name
+func (l labelKeys) validate(v *validation.Validator, path, key string) {
+	name := alert.LabelName(key)
+	if other, found := l[name]; found {
+		v.Check(other == key, path, "%q and %q are both named %q in the alerts", other, key, name)
+		return
+	}
+	l[name] = key
+}
+
+// merge returns the static entries and the allowed entries of the namespace then of the certificate, nil when there is
+// none
+func (c LabelsConfig) merge(certificate, namespace map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range c.Static {
+		merged[alert.LabelName(k)] = v
+	}
+	for _, k := range c.Namespace {
+		if v, found := namespace[k]; found {
+			merged[alert.LabelName(k)] = v
+		}
+	}
+	for _, k := range c.Certificate {
+		if v, found := certificate[k]; found {
+			merged[alert.LabelName(k)] = v
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// Validate adds the violations of the configuration to v
+func (c Config) Validate(v *validation.Validator) {
+	v.NotNegativeDuration("threshold", c.Threshold)
+	gatherer := v.Section("gatherer")
+	gatherer.Check(c.GathererConfig.PageSize > 0, "page_size", "must be positive, got %d", c.GathererConfig.PageSize)
+	// the list calls time out instantly without timeout
+	gatherer.Positive("timeout", c.GathererConfig.Timeout)
+	flapping := v.Section("flapping")
+	flapping.NotNegativeDuration("window", c.Flapping.Window)
+	flapping.NotNegative("threshold", int64(c.Flapping.Threshold))
+	c.Labels.validate(v.Section("labels"))
+	c.Annotations.validate(v.Section("annotations"))
+}
+
+// FlappingConfig contains the configuration of the detection of the certificates going repeatedly into WARN or ERROR,
+// e.g. when the renewal fails each cycle. It is based on the alert history stored in Pinot.
+type FlappingConfig struct {
+	// Window defines the duration of the alert history analyzed, the detection is disabled when not set
+	Window time.Duration `yaml:"window"`
+	// Threshold defines the number of renewal cycles, i.e. distinct expirations, going into WARN or ERROR within the
+	// window from which a certificate is flapping. DefaultFlappingThreshold is used when not set.
+	Threshold int `yaml:"threshold"`
 }
 
 // GathererConfig contains the configuration for fetching the certificate info
@@ -37,4 +199,8 @@ type GathererConfig struct {
 	PageSize int64          `yaml:"page_size"`
 	// Timeout defines the timeout to fetch a page
 	Timeout  time.Duration `yaml:"timeout"`
+	// ReadSecrets enables reading the X.509 certificate stored in the secret of each certificate to extract its
+	// serial number, fingerprint and key details. The TLS secrets are listed once per namespace, which requires the
+	// permission to list secrets and pulls their private keys into the process.
+	ReadSecrets bool `yaml:"read_secrets"`
 }
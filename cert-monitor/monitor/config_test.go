@@ -0,0 +1,96 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package monitor_test
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
+	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+
+	Describe("Validate", func() {
+		var v *validation.Validator
+
+		BeforeEach(func() {
+			v = validation.New()
+		})
+
+		It("should accept a valid configuration", func() {
+			monitor.Config{
+				Threshold:      time.Hour,
+				GathererConfig: monitor.GathererConfig{PageSize: 100, Timeout: 10 * time.Second},
+			}.Validate(v.Section("monitor"))
+			Expect(v.Err()).ShouldNot(HaveOccurred())
+		})
+
+		It("should report all the violations with their path", func() {
+			monitor.Config{
+				Threshold: -time.Hour,
+				Flapping:  monitor.FlappingConfig{Threshold: -1},
+				Labels: monitor.LabelsConfig{
+					Static:    map[string]string{"env": "prod", "example.com/owner": "a"},
+					Namespace: []string{"team", "", "example_com/owner"},
+				},
+				Annotations: monitor.LabelsConfig{Certificate: []string{"app.kubernetes.io/name"}},
+			}.Validate(v.Section("monitor"))
+			err := v.Err()
+			Expect(err).Should(BeAssignableToTypeOf(&validation.Error{}))
+			Expect(err.(*validation.Error).Violations).Should(Equal([]validation.Violation{
+				{Path: "monitor.threshold", Message: "must not be negative, got -1h0m0s"},
+				{Path: "monitor.gatherer.page_size", Message: "must be positive, got 0"},
+				{Path: "monitor.gatherer.timeout", Message: "must be positive, got 0s"},
+				{Path: "monitor.flapping.threshold", Message: "must not be negative, got -1"},
+				{Path: "monitor.labels.namespace[1]", Message: "must not be empty"},
+				{Path: "monitor.labels.namespace[2]",
+					Message: `"example.com/owner" and "example_com/owner" are both named "example_com_owner" in the alerts`},
+			}))
+		})
+
+		It("should accept the prefixed keys", func() {
+			monitor.Config{
+				Threshold:      time.Hour,
+				GathererConfig: monitor.GathererConfig{PageSize: 100, Timeout: 10 * time.Second},
+				Labels: monitor.LabelsConfig{
+					Static:      map[string]string{"example.com/owner": "a"},
+					Certificate: []string{"app.kubernetes.io/name", "example.com/owner"},
+					Namespace:   []string{"app.kubernetes.io/name"},
+				},
+			}.Validate(v.Section("monitor"))
+			Expect(v.Err()).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Describe("LabelsConfig", func() {
+		It("should return the sorted names of the keys in the alerts", func() {
+			labels := monitor.LabelsConfig{
+				Static:      map[string]string{"env": "prod", "example.com/owner": "a"},
+				Certificate: []string{"app.kubernetes.io/name", "team"},
+				Namespace:   []string{"team"},
+			}
+			Expect(labels.Keys()).Should(Equal([]string{"app_kubernetes_io_name", "env", "example_com_owner", "team"}))
+		})
+	})
+})
@@ -23,22 +23,34 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+
+	certmanagerv1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
 	certmanager "github.com/jetstack/cert-manager/pkg/client/clientset/versioned"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
-func NewKubernetesCertificateInfoGatherer(logger *zap.SugaredLogger, clientSet certmanager.Interface, cfg GathererConfig) CertificateInfoGatherer {
+// NewKubernetesCertificateInfoGatherer returns a CertificateInfoGatherer listing the cert-manager certificates. The
+// X.509 certificates are read from the secrets to complete the metadata when secrets is not nil, and the labels and
+// annotations of the namespaces are read when namespaces is not nil.
+func NewKubernetesCertificateInfoGatherer(logger *zap.SugaredLogger, clientSet certmanager.Interface, secrets corev1client.SecretsGetter, namespaces corev1client.NamespacesGetter, cfg GathererConfig) CertificateInfoGatherer {
 	return &k8sCertificateInfoGatherer{
-		cfg:       cfg,
-		clientSet: clientSet,
-		logger:    logger,
+		cfg:        cfg,
+		clientSet:  clientSet,
+		secrets:    secrets,
+		namespaces: namespaces,
+		logger:     logger,
 	}
 }
 
 type k8sCertificateInfoGatherer struct {
-	cfg       GathererConfig
-	clientSet certmanager.Interface
+	cfg        GathererConfig
+	clientSet  certmanager.Interface
+	secrets    corev1client.SecretsGetter
+	namespaces corev1client.NamespacesGetter
 
 	logger *zap.SugaredLogger
 }
@@ -49,6 +61,8 @@ func (k *k8sCertificateInfoGatherer) GatherCertificateInfos(parentCtx context.Co
 		continueToken string
 		page          = 1
 		certInfos     []CertificateInfo
+		namespaces    = map[string]*corev1.Namespace{}
+		secrets       = map[string]map[string][]byte{}
 	)
 
 	for {
@@ -63,11 +77,20 @@ func (k *k8sCertificateInfoGatherer) GatherCertificateInfos(parentCtx context.Co
 		}
 		k.logger.Infow("fetched certificate CRD", "size", len(certs.Items), "page", page)
 		for _, cert := range certs.Items {
-			certInfos = append(certInfos, CertificateInfo{
-				Name:       cert.Name,
-				Namespace:  cert.Namespace,
-				Expiration: cert.Status.NotAfter.UnixNano(),
-			})
+			certInfo := CertificateInfo{
+				Name:        cert.Name,
+				Namespace:   cert.Namespace,
+				UID:         string(cert.UID),
+				Expiration:  cert.Status.NotAfter.UnixNano(),
+				Metadata:    k.metadata(parentCtx, secrets, cert),
+				Labels:      cert.Labels,
+				Annotations: cert.Annotations,
+			}
+			if namespace := k.namespace(parentCtx, namespaces, cert.Namespace); namespace != nil {
+				certInfo.NamespaceLabels = namespace.Labels
+				certInfo.NamespaceAnnotations = namespace.Annotations
+			}
+			certInfos = append(certInfos, certInfo)
 		}
 		if certs.GetContinue() == "" {
 			break
@@ -78,3 +101,99 @@ func (k *k8sCertificateInfoGatherer) GatherCertificateInfos(parentCtx context.Co
 
 	return certInfos, nil
 }
+
+// metadata returns the metadata of the certificate from its spec and status, completed by the X.509 certificate of its
+// secret when the secrets are read. The metadata of the spec is kept when the secret cannot be read.
+func (k *k8sCertificateInfoGatherer) metadata(parentCtx context.Context, secrets map[string]map[string][]byte, cert certmanagerv1.Certificate) alert.CertificateMetadata {
+	metadata := alert.CertificateMetadata{
+		IssuerName: cert.Spec.IssuerRef.Name,
+		IssuerKind: cert.Spec.IssuerRef.Kind,
+		SecretName: cert.Spec.SecretName,
+		DNSNames:   cert.Spec.DNSNames,
+	}
+	if metadata.IssuerKind == "" {
+		// cert-manager defaults to a namespaced issuer
+		metadata.IssuerKind = "Issuer"
+	}
+	if cert.Spec.PrivateKey != nil {
+		metadata.KeyAlgorithm = string(cert.Spec.PrivateKey.Algorithm)
+		metadata.KeySize = cert.Spec.PrivateKey.Size
+	}
+	if cert.Status.NotBefore != nil {
+		metadata.NotBefore = cert.Status.NotBefore.UnixNano()
+	}
+	if cert.Status.NotAfter != nil {
+		metadata.NotAfter = cert.Status.NotAfter.UnixNano()
+	}
+	if k.secrets == nil || cert.Spec.SecretName == "" {
+		return metadata
+	}
+
+	certificates := k.tlsCertificates(parentCtx, secrets, cert.Namespace)
+	if certificates == nil {
+		return metadata
+	}
+	data, found := certificates[cert.Spec.SecretName]
+	if !found {
+		k.logger.Warnw("certificate secret not found", "namespace", cert.Namespace, "secret", cert.Spec.SecretName)
+		return metadata
+	}
+	if err := readX509Metadata(data, &metadata); err != nil {
+		k.logger.Warnw("failed to parse certificate", "namespace", cert.Namespace, "secret", cert.Spec.SecretName, "error", err)
+	}
+	return metadata
+}
+
+// tlsCertificates returns the X.509 certificates of the TLS secrets of the namespace keyed by secret name, they are
+// listed once per gathering and cached. The private keys are listed along but not kept. It returns nil when the secrets
+// cannot be listed.
+func (k *k8sCertificateInfoGatherer) tlsCertificates(parentCtx context.Context, cache map[string]map[string][]byte, namespace string) map[string][]byte {
+	if certificates, found := cache[namespace]; found {
+		return certificates
+	}
+	certificates := map[string][]byte{}
+	var continueToken string
+	for {
+		ctx, cancel := context.WithTimeout(parentCtx, k.cfg.Timeout)
+		secrets, err := k.secrets.Secrets(namespace).List(ctx, v1.ListOptions{
+			FieldSelector: "type=" + string(corev1.SecretTypeTLS),
+			Limit:         k.cfg.PageSize,
+			Continue:      continueToken,
+		})
+		cancel()
+		if err != nil {
+			k.logger.Warnw("failed to list certificate secrets", "namespace", namespace, "error", err)
+			certificates = nil
+			break
+		}
+		for _, secret := range secrets.Items {
+			certificates[secret.Name] = secret.Data[corev1.TLSCertKey]
+		}
+		if secrets.GetContinue() == "" {
+			break
+		}
+		continueToken = secrets.GetContinue()
+	}
+	cache[namespace] = certificates
+	return certificates
+}
+
+// namespace returns the namespace, it is read once per gathering and cached. It returns nil when the namespaces are not
+// read or the namespace cannot be read.
+func (k *k8sCertificateInfoGatherer) namespace(parentCtx context.Context, cache map[string]*corev1.Namespace, name string) *corev1.Namespace {
+	if k.namespaces == nil {
+		return nil
+	}
+	if namespace, found := cache[name]; found {
+		return namespace
+	}
+	ctx, cancel := context.WithTimeout(parentCtx, k.cfg.Timeout)
+	defer cancel()
+	namespace, err := k.namespaces.Namespaces().Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		k.logger.Warnw("failed to read namespace", "namespace", name, "error", err)
+		namespace = nil
+	}
+	cache[name] = namespace
+	return namespace
+}
@@ -22,7 +22,9 @@ package monitor_test
 import (
 	"context"
 	"errors"
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
 	v1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
 	"github.com/stretchr/testify/mock"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"time"
@@ -50,7 +52,7 @@ var _ = Describe("k8sCertificateInfoGatherer", func() {
 		clientSetMock.On("CertmanagerV1").Return(certManagerMock)
 		certAPIMock = &mocks.CertificateInterface{}
 		certManagerMock.On("Certificates", "").Return(certAPIMock)
-		gatherer = monitor.NewKubernetesCertificateInfoGatherer(zap.S(), clientSetMock, monitor.GathererConfig{
+		gatherer = monitor.NewKubernetesCertificateInfoGatherer(zap.S(), clientSetMock, nil, nil, monitor.GathererConfig{
 			PageSize: 1,
 			Timeout:  time.Second,
 		})
@@ -78,12 +80,21 @@ var _ = Describe("k8sCertificateInfoGatherer", func() {
 				certList := &v1.CertificateList{
 					Items: []v1.Certificate{
 						{
+							Spec: v1.CertificateSpec{
+								IssuerRef:  cmmeta.ObjectReference{Name: "ca", Kind: "ClusterIssuer"},
+								SecretName: "cert-tls",
+								DNSNames:   []string{"example.com"},
+								PrivateKey: &v1.CertificatePrivateKey{Algorithm: v1.ECDSAKeyAlgorithm, Size: 256},
+							},
 							Status: v1.CertificateStatus{
 								NotAfter: &expiry,
 							},
 							ObjectMeta: metav1.ObjectMeta{
-								Name:      "cert",
-								Namespace: "ns",
+								Name:        "cert",
+								Namespace:   "ns",
+								UID:         "uid",
+								Labels:      map[string]string{"team": "a"},
+								Annotations: map[string]string{"runbook": "b"},
 							},
 						},
 					},
@@ -101,7 +112,19 @@ var _ = Describe("k8sCertificateInfoGatherer", func() {
 				Expect(certs).Should(ContainElements(monitor.CertificateInfo{
 					Namespace:  "ns",
 					Name:       "cert",
-					Expiration: expiry.UnixNano(),
+					UID:         "uid",
+					Expiration:  expiry.UnixNano(),
+					Labels:      map[string]string{"team": "a"},
+					Annotations: map[string]string{"runbook": "b"},
+					Metadata: alert.CertificateMetadata{
+						IssuerName:   "ca",
+						IssuerKind:   "ClusterIssuer",
+						SecretName:   "cert-tls",
+						DNSNames:     []string{"example.com"},
+						KeyAlgorithm: "ECDSA",
+						KeySize:      256,
+						NotAfter:     expiry.UnixNano(),
+					},
 				}))
 			})
 
@@ -163,11 +186,13 @@ var _ = Describe("k8sCertificateInfoGatherer", func() {
 						Namespace:  "ns1",
 						Name:       "cert1",
 						Expiration: expiry.UnixNano(),
+						Metadata:   alert.CertificateMetadata{IssuerKind: "Issuer", NotAfter: expiry.UnixNano()},
 					},
 					monitor.CertificateInfo{
 						Name:       "cert2",
 						Namespace:  "ns2",
 						Expiration: expiry.UnixNano(),
+						Metadata:   alert.CertificateMetadata{IssuerKind: "Issuer", NotAfter: expiry.UnixNano()},
 					}))
 			})
 		})
@@ -0,0 +1,137 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+)
+
+// MessageData contains the data available to the message templates
+type MessageData struct {
+	// Name of the certificate in k8s
+	Name string
+	// Namespace where the certificate is defined
+	Namespace string
+	// UID of the certificate in k8s
+	UID string
+	// Level is the level of the alert, e.g. WARN
+	Level string
+	// Expiration defines when the certificate expires
+	Expiration time.Time
+	// Remaining is the duration until the certificate expires, it is negative once the certificate is expired
+	Remaining time.Duration
+	// Threshold is the duration before the expiration from which the certificate is close to expiration
+	Threshold time.Duration
+	// Labels contains the labels of the alert
+	Labels map[string]string
+	// Annotations contains the annotations of the alert
+	Annotations map[string]string
+	// Certificate contains the metadata of the certificate
+	Certificate alert.CertificateMetadata
+}
+
+// MessageTemplate is a Go template rendering the message of the alerts from MessageData, the humanize function
+// formats a duration in words, e.g. `Cert {{.Namespace}}/{{.Name}} expires in {{humanize .Remaining}}`
+type MessageTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseMessageTemplate parses the template and verifies it renders the MessageData, so referencing an unknown field
+// fails when the configuration is loaded rather than when the alerts are sent. The missing labels and annotations
+// render as empty strings.
+func ParseMessageTemplate(text string) (*MessageTemplate, error) {
+	tmpl, err := template.New("message").
+		Funcs(template.FuncMap{"humanize": humanize}).
+		Option("missingkey=zero").
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message template: %w", err)
+	}
+	if err := tmpl.Execute(ioutil.Discard, MessageData{}); err != nil {
+		return nil, fmt.Errorf("failed to render message template: %w", err)
+	}
+	return &MessageTemplate{tmpl: tmpl}, nil
+}
+
+// UnmarshalText parses the template, it implements encoding.TextUnmarshaler
+func (t *MessageTemplate) UnmarshalText(text []byte) error {
+	parsed, err := ParseMessageTemplate(string(text))
+	if err != nil {
+		return err
+	}
+	*t = *parsed
+	return nil
+}
+
+// Render returns the message rendered with the data
+func (t *MessageTemplate) Render(data MessageData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render message template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+var humanizeUnits = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+// humanize returns the absolute value of the duration in words with at most its two most significant units, e.g.
+// 3 days 4 hours
+func humanize(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	for i, unit := range humanizeUnits {
+		n := int64(d / unit.duration)
+		if n == 0 {
+			continue
+		}
+		parts := []string{plural(n, unit.name)}
+		if i+1 < len(humanizeUnits) {
+			next := humanizeUnits[i+1]
+			if m := int64(d % unit.duration / next.duration); m > 0 {
+				parts = append(parts, plural(m, next.name))
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	return "0 seconds"
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
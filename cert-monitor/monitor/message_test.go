@@ -0,0 +1,106 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package monitor_test
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+
+	"gopkg.in/yaml.v3"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MessageTemplate", func() {
+
+	Describe("UnmarshalText", func() {
+		It("should parse the templates of the config", func() {
+			var cfg monitor.Config
+			Expect(yaml.Unmarshal([]byte(`
+messages:
+  warn: "Cert {{.Namespace}}/{{.Name}} expires in {{humanize .Remaining}}"
+`), &cfg)).Should(Succeed())
+			Expect(cfg.Messages.Info).Should(BeNil())
+			Expect(cfg.Messages.Warn).ShouldNot(BeNil())
+			message, err := cfg.Messages.Warn.Render(monitor.MessageData{
+				Name:      "cert",
+				Namespace: "ns",
+				Remaining: 76*time.Hour + 5*time.Minute,
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(message).Should(Equal("Cert ns/cert expires in 3 days 4 hours"))
+		})
+
+		It("should reject a template which does not parse", func() {
+			var cfg monitor.Config
+			err := yaml.Unmarshal([]byte(`
+messages:
+  error: "expired {{.Name"
+`), &cfg)
+			Expect(err).Should(MatchError(ContainSubstring("failed to parse message template")))
+		})
+
+		It("should reject a template referencing an unknown field", func() {
+			var cfg monitor.Config
+			err := yaml.Unmarshal([]byte(`
+messages:
+  error: "expired {{.Unknown}}"
+`), &cfg)
+			Expect(err).Should(MatchError(ContainSubstring("failed to render message template")))
+		})
+	})
+
+	Describe("Render", func() {
+		It("should humanize the durations", func() {
+			tmpl, err := monitor.ParseMessageTemplate("{{humanize .Remaining}}")
+			Expect(err).ShouldNot(HaveOccurred())
+			for d, expected := range map[time.Duration]string{
+				0:                          "0 seconds",
+				42 * time.Second:           "42 seconds",
+				time.Minute + time.Second:  "1 minute 1 second",
+				48*time.Hour + time.Minute: "2 days",
+				-90 * time.Minute:          "1 hour 30 minutes",
+			} {
+				Expect(tmpl.Render(monitor.MessageData{Remaining: d})).Should(Equal(expected))
+			}
+		})
+
+		It("should give access to the labels and thresholds", func() {
+			tmpl, err := monitor.ParseMessageTemplate(`{{index .Labels "team"}}: {{.Level}} within {{humanize .Threshold}}`)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(tmpl.Render(monitor.MessageData{
+				Level:     "WARN",
+				Threshold: 720 * time.Hour,
+				Labels:    map[string]string{"team": "payments"},
+			})).Should(Equal("payments: WARN within 30 days"))
+		})
+
+		It("should render the missing labels as empty strings", func() {
+			tmpl, err := monitor.ParseMessageTemplate(`[{{.Labels.team}}] {{.Name}}`)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(tmpl.Render(monitor.MessageData{
+				Name:   "cert",
+				Labels: map[string]string{"env": "prod"},
+			})).Should(Equal("[] cert"))
+		})
+	})
+})
@@ -23,21 +23,45 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"time"
 
 	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
 
 	"go.uber.org/zap"
 )
 
+const (
+	// CertificateKind is the kind of the cert-manager certificates
+	CertificateKind = "Certificate"
+	// CertificateAPIVersion is the API version of the cert-manager certificates
+	CertificateAPIVersion = "cert-manager.io/v1"
+	// DefaultFlappingThreshold is the number of renewal cycles going into WARN or ERROR from which a certificate is
+	// flapping when none is configured
+	DefaultFlappingThreshold = 3
+)
+
 // CertificateInfo contains the name, namespace and the expiration of a certificate declared in k8s
 type CertificateInfo struct {
 	// Name of the certificate in k8s
 	Name string
 	// Namespace where the certificate is defined
 	Namespace string
+	// UID of the certificate in k8s
+	UID string
 	// Expiration defines the timestamp of when the certificate will expire in nanoseconds since the epoch
 	Expiration int64
+	// Metadata contains the metadata of the certificate added to the alerts
+	Metadata alert.CertificateMetadata
+	// Labels contains the labels of the certificate in k8s
+	Labels map[string]string
+	// Annotations contains the annotations of the certificate in k8s
+	Annotations map[string]string
+	// NamespaceLabels contains the labels of the namespace of the certificate when known
+	NamespaceLabels map[string]string
+	// NamespaceAnnotations contains the annotations of the namespace of the certificate when known
+	NamespaceAnnotations map[string]string
 }
 
 // CertificateInfoGatherer collects information about the certificates defined in k8s
@@ -50,18 +74,33 @@ type Clock interface {
 	Now() int64
 }
 
-func NewCertificateMonitor(logger *zap.SugaredLogger, gatherer CertificateInfoGatherer, notifier alert.Notifier, clock Clock, threshold time.Duration) *CertificateMonitor {
+// NewCertificateMonitor returns a CertificateMonitor, the flapping detection requires the alert history and is disabled
+// when history is nil
+func NewCertificateMonitor(logger *zap.SugaredLogger, gatherer CertificateInfoGatherer, notifier alert.Notifier, history *pinot.AlertHistory, clock Clock, cfg Config) *CertificateMonitor {
 	hostname, err := os.Hostname()
 	if err != nil {
 		logger.Warn("failed to determine hostname, using unknown value")
 		hostname = "unknown"
 	}
+	flapping := cfg.Flapping
+	if flapping.Threshold <= 0 {
+		flapping.Threshold = DefaultFlappingThreshold
+	}
+	if flapping.Window <= 0 {
+		history = nil
+	}
 	return &CertificateMonitor{
 		hostname:                hostname,
 		clock:                   clock,
-		threshold:               threshold.Nanoseconds(),
+		threshold:               cfg.Threshold.Nanoseconds(),
+		recovery:                cfg.Recovery,
+		flapping:                flapping,
+		labels:                  cfg.Labels,
+		annotations:             cfg.Annotations,
+		messages:                cfg.Messages,
 		certificateInfoGatherer: gatherer,
 		notifier:                notifier,
+		history:                 history,
 		logger:                  logger,
 	}
 }
@@ -70,10 +109,17 @@ func NewCertificateMonitor(logger *zap.SugaredLogger, gatherer CertificateInfoGa
 type CertificateMonitor struct {
 	hostname  string
 	threshold int64
+	recovery  bool
+	flapping  FlappingConfig
+
+	labels      LabelsConfig
+	annotations LabelsConfig
+	messages    MessagesConfig
 
 	clock                   Clock
 	certificateInfoGatherer CertificateInfoGatherer
 	notifier                alert.Notifier
+	history                 *pinot.AlertHistory
 
 	logger *zap.SugaredLogger
 }
@@ -86,42 +132,153 @@ func (cm *CertificateMonitor) CheckCertificates(ctx context.Context) error {
 
 	size := len(certInfos)
 	cm.logger.Infow("verifying certificates", "size", size)
-	var alerts []alert.Alert
+	var (
+		alerts   []alert.Alert
+		problems int
+	)
 	for _, cert := range certInfos {
 		now := cm.clock.Now()
 		delta := cert.Expiration - now
 		if delta <= 0 {
-			alerts = append(alerts, alert.Alert{
-				Level: alert.Error,
-				ObjectRef: alert.ObjectRef{
-					Namespace: cert.Namespace,
-					Name:      cert.Name,
-				},
-				Message: "certificate expired",
-				When:    now,
-				Source:  cm.hostname,
-			})
+			alerts = append(alerts, cm.newAlert(alert.Error, "certificate expired", cert, now))
+			problems++
 		} else if delta <= cm.threshold {
-			alerts = append(alerts, alert.Alert{
-				Level: alert.Warn,
-				ObjectRef: alert.ObjectRef{
-					Namespace: cert.Namespace,
-					Name:      cert.Name,
-				},
-				Message: "certificate is about to expire",
-				When:    now,
-				Source:  cm.hostname,
-			})
+			alerts = append(alerts, cm.newAlert(alert.Warn, "certificate is about to expire", cert, now))
+			problems++
+		} else if cm.recovery {
+			alerts = append(alerts, cm.newAlert(alert.Info, "certificate is valid", cert, now))
 		}
 	}
-	if len(alerts) == 0 {
+	if problems == 0 {
 		cm.logger.Infow("all certificates are valid and not close to expiration", "size", size)
+	} else if cm.history != nil {
+		alerts = append(alerts, cm.flappingAlerts(ctx, alerts)...)
+	}
+	if len(alerts) == 0 {
 		return nil
 	}
-	return cm.notify(alerts)
+	return cm.notify(ctx, alerts)
+}
+
+// flappingAlerts returns an ERROR alert for each certificate in WARN or ERROR which went into WARN or ERROR for at
+// least the threshold of renewal cycles over the window, the current cycle included. The detection is skipped when the
+// alert history cannot be queried.
+func (cm *CertificateMonitor) flappingAlerts(ctx context.Context, alerts []alert.Alert) []alert.Alert {
+	now := cm.clock.Now()
+	cycles, err := cm.history.ProblemCycles(ctx, time.Unix(0, now).Add(-cm.flapping.Window))
+	if err != nil {
+		cm.logger.Warnw("failed to query alert history, skipping flapping detection", "error", err)
+		return nil
+	}
+	expirations := map[string]map[int64]struct{}{}
+	for _, cycle := range cycles {
+		key := cycle.Namespace + "/" + cycle.Name
+		if expirations[key] == nil {
+			expirations[key] = map[int64]struct{}{}
+		}
+		expirations[key][cycle.Expiration] = struct{}{}
+	}
+
+	var flapping []alert.Alert
+	for _, a := range alerts {
+		if a.Level < alert.Warn {
+			continue
+		}
+		key := a.ObjectRef.Namespace + "/" + a.ObjectRef.Name
+		if expirations[key] == nil {
+			expirations[key] = map[int64]struct{}{}
+		}
+		expirations[key][a.Expiration] = struct{}{}
+		if count := len(expirations[key]); count >= cm.flapping.Threshold {
+			flappingAlert := a
+			flappingAlert.Level = alert.Error
+			flappingAlert.Message = fmt.Sprintf("certificate is flapping: %d renewal cycles went into WARN or ERROR over the last %s", count, cm.flapping.Window)
+			flapping = append(flapping, flappingAlert)
+		}
+	}
+	return flapping
+}
+
+// newAlert returns the alert of the certificate with its metadata, labels and annotations. The message is rendered
+// with the template of the level, defaultMessage is used when there is none or it fails.
+func (cm *CertificateMonitor) newAlert(level alert.Level, defaultMessage string, cert CertificateInfo, now int64) alert.Alert {
+	a := alert.Alert{
+		Level:            level,
+		ObjectRef:        certificateRef(cert),
+		When:             now,
+		Source:           cm.hostname,
+		Expiration:       cert.Expiration,
+		RemainingSeconds: (cert.Expiration - now) / int64(time.Second),
+		Labels:           cm.labels.merge(cert.Labels, cert.NamespaceLabels),
+		Annotations:      cm.annotations.merge(cert.Annotations, cert.NamespaceAnnotations),
+	}
+	if !reflect.ValueOf(cert.Metadata).IsZero() {
+		metadata := cert.Metadata
+		a.Certificate = &metadata
+	}
+	a.Message = cm.message(a, defaultMessage, cert, now)
+	return a
+}
+
+// message returns the message of the alert rendered with the template of its level, defaultMessage when there is no
+// template or it fails to render
+func (cm *CertificateMonitor) message(a alert.Alert, defaultMessage string, cert CertificateInfo, now int64) string {
+	tmpl := cm.messages.template(a.Level)
+	if tmpl == nil {
+		return defaultMessage
+	}
+	message, err := tmpl.Render(MessageData{
+		Name:        cert.Name,
+		Namespace:   cert.Namespace,
+		UID:         cert.UID,
+		Level:       a.Level.String(),
+		Expiration:  time.Unix(0, cert.Expiration).UTC(),
+		Remaining:   time.Duration(cert.Expiration - now),
+		Threshold:   time.Duration(cm.threshold),
+		Labels:      a.Labels,
+		Annotations: a.Annotations,
+		Certificate: cert.Metadata,
+	})
+	if err != nil {
+		cm.logger.Warnw("failed to render message, using the default one", "namespace", cert.Namespace, "name", cert.Name, "error", err)
+		return defaultMessage
+	}
+	return message
+}
+
+// certificateRef returns the reference to the cert-manager Certificate object
+func certificateRef(cert CertificateInfo) alert.ObjectRef {
+	return alert.ObjectRef{
+		Namespace:  cert.Namespace,
+		Name:       cert.Name,
+		Kind:       CertificateKind,
+		APIVersion: CertificateAPIVersion,
+		UID:        cert.UID,
+	}
 }
 
-func (cm *CertificateMonitor) notify(b []alert.Alert) error {
+func (cm *CertificateMonitor) notify(ctx context.Context, b []alert.Alert) error {
+	if err := cm.send(b); err != nil {
+		return err
+	}
+	if asyncNotifier, ok := cm.notifier.(alert.AsyncNotifier); ok {
+		cm.logger.Infow("waiting for alerts delivery", "size", len(b))
+		if err := asyncNotifier.Flush(ctx); err != nil {
+			return fmt.Errorf("failed to deliver alerts: %w", err)
+		}
+	}
+	return nil
+}
+
+func (cm *CertificateMonitor) send(b []alert.Alert) error {
+	if batchNotifier, ok := cm.notifier.(alert.BatchNotifier); ok {
+		cm.logger.Infow("sending notifications for alerts", "size", len(b))
+		if err := batchNotifier.SendBatch(b); err != nil {
+			return fmt.Errorf("failed to send %d alerts: %w", len(b), err)
+		}
+		return nil
+	}
+
 	for _, a := range b {
 		cm.logger.Infow("sending notification for alert",
 			"message", a.Message,
@@ -20,13 +20,19 @@
 package monitor_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
 	"github.com/stretchr/testify/mock"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	"github.com/dvergnes/pinot-playground/cert-monitor/mocks"
 	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -47,7 +53,7 @@ var _ = Describe("Monitor", func() {
 		gathererMock = &mocks.CertificateInfoGatherer{}
 		clockMock = &mocks.Clock{}
 		notifierMock = &mocks.Notifier{}
-		m = monitor.NewCertificateMonitor(zap.S(), gathererMock, notifierMock, clockMock, threshold)
+		m = monitor.NewCertificateMonitor(zap.S(), gathererMock, notifierMock, nil, clockMock, monitor.Config{Threshold: threshold})
 	})
 
 	AfterEach(func() {
@@ -59,11 +65,11 @@ var _ = Describe("Monitor", func() {
 	Describe("CheckCertificates", func() {
 		var err error
 		JustBeforeEach(func() {
-			err = m.CheckCertificates(nil)
+			err = m.CheckCertificates(context.Background())
 		})
 		When("no certificates defined in the system", func() {
 			BeforeEach(func() {
-				gathererMock.On("GatherCertificateInfos").Return(nil, nil)
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return(nil, nil)
 			})
 			It("should not alert", func() {
 				Expect(err).ShouldNot(HaveOccurred())
@@ -73,7 +79,7 @@ var _ = Describe("Monitor", func() {
 
 		When("certificates are valid and not close to expiration", func() {
 			BeforeEach(func() {
-				gathererMock.On("GatherCertificateInfos").Return([]monitor.CertificateInfo{
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
 					{
 						Name:       "cert-name",
 						Namespace:  "ns",
@@ -88,19 +94,51 @@ var _ = Describe("Monitor", func() {
 			})
 		})
 
-		When("certificate is expired", func() {
+		When("certificates are valid and recovery is enabled", func() {
 			BeforeEach(func() {
-				gathererMock.On("GatherCertificateInfos").Return([]monitor.CertificateInfo{
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, notifierMock, nil, clockMock, monitor.Config{
+					Threshold: threshold,
+					Recovery:  true,
+				})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
 					{
 						Name:       "cert-name",
 						Namespace:  "ns",
-						Expiration: 0,
+						Expiration: time.Hour.Nanoseconds(),
 					},
 				}, nil)
 				clockMock.On("Now").Return(int64(100))
 				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
 					Expect(a.ObjectRef.Name).Should(Equal("cert-name"))
 					Expect(a.ObjectRef.Namespace).Should(Equal("ns"))
+					return Expect(a.Level).Should(Equal(alert.Info))
+				})).Return(nil).Once()
+			})
+			It("should alert at info level", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				// other assertions are made on the notifier mock
+			})
+		})
+
+		When("certificate is expired", func() {
+			BeforeEach(func() {
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:       "cert-name",
+						Namespace:  "ns",
+						UID:        "uid",
+						Expiration: 0,
+					},
+				}, nil)
+				clockMock.On("Now").Return(int64(100))
+				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
+					Expect(a.ObjectRef).Should(Equal(alert.ObjectRef{
+						Name:       "cert-name",
+						Namespace:  "ns",
+						Kind:       "Certificate",
+						APIVersion: "cert-manager.io/v1",
+						UID:        "uid",
+					}))
 					return Expect(a.Level).Should(Equal(alert.Error))
 				})).Return(nil).Once()
 			})
@@ -113,7 +151,7 @@ var _ = Describe("Monitor", func() {
 		When("certificate is close to expiration", func() {
 			BeforeEach(func() {
 				now := int64(100)
-				gathererMock.On("GatherCertificateInfos").Return([]monitor.CertificateInfo{
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
 					{
 						Name:       "cert-name",
 						Namespace:  "ns",
@@ -124,6 +162,7 @@ var _ = Describe("Monitor", func() {
 				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
 					Expect(a.ObjectRef.Name).Should(Equal("cert-name"))
 					Expect(a.ObjectRef.Namespace).Should(Equal("ns"))
+					Expect(a.Expiration).Should(Equal(int64(threshold) + now))
 					return Expect(a.Level).Should(Equal(alert.Warn))
 				})).Return(nil).Once()
 			})
@@ -133,10 +172,274 @@ var _ = Describe("Monitor", func() {
 			})
 		})
 
+		When("labels and annotations are configured", func() {
+			BeforeEach(func() {
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, notifierMock, nil, clockMock, monitor.Config{
+					Threshold: threshold,
+					Labels: monitor.LabelsConfig{
+						Static:      map[string]string{"env": "prod", "team": "platform"},
+						Certificate: []string{"app"},
+						Namespace:   []string{"team"},
+					},
+					Annotations: monitor.LabelsConfig{
+						Certificate: []string{"runbook"},
+					},
+				})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:                 "cert-name",
+						Namespace:            "ns",
+						Labels:               map[string]string{"app": "api", "internal": "true"},
+						Annotations:          map[string]string{"runbook": "https://runbook"},
+						NamespaceLabels:      map[string]string{"team": "payments", "app": "ns-app"},
+						NamespaceAnnotations: map[string]string{"owner": "payments@example.com"},
+					},
+				}, nil)
+				clockMock.On("Now").Return(int64(100))
+				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
+					Expect(a.Labels).Should(Equal(map[string]string{"env": "prod", "team": "payments", "app": "api"}))
+					return Expect(a.Annotations).Should(Equal(map[string]string{"runbook": "https://runbook"}))
+				})).Return(nil).Once()
+			})
+			It("should add the allowed labels and annotations to the alert", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				// other assertions are made on the notifier mock
+			})
+		})
+
+		When("prefixed labels and annotations are configured", func() {
+			BeforeEach(func() {
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, notifierMock, nil, clockMock, monitor.Config{
+					Threshold: threshold,
+					Labels: monitor.LabelsConfig{
+						Static:      map[string]string{"example.com/env": "prod"},
+						Certificate: []string{"app.kubernetes.io/name"},
+					},
+					Annotations: monitor.LabelsConfig{
+						Namespace: []string{"example.com/owner"},
+					},
+				})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:                 "cert-name",
+						Namespace:            "ns",
+						Labels:               map[string]string{"app.kubernetes.io/name": "api"},
+						NamespaceAnnotations: map[string]string{"example.com/owner": "payments@example.com"},
+					},
+				}, nil)
+				clockMock.On("Now").Return(int64(100))
+				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
+					Expect(a.Labels).Should(Equal(map[string]string{"example_com_env": "prod", "app_kubernetes_io_name": "api"}))
+					return Expect(a.Annotations).Should(Equal(map[string]string{"example_com_owner": "payments@example.com"}))
+				})).Return(nil).Once()
+			})
+			It("should replace the dots and slashes of the keys with underscores", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				// other assertions are made on the notifier mock
+			})
+		})
+
+		When("message templates are configured", func() {
+			BeforeEach(func() {
+				warn, err := monitor.ParseMessageTemplate("Cert {{.Namespace}}/{{.Name}} expires in {{humanize .Remaining}}")
+				Expect(err).ShouldNot(HaveOccurred())
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, notifierMock, nil, clockMock, monitor.Config{
+					Threshold: threshold,
+					Messages:  monitor.MessagesConfig{Warn: warn},
+				})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:       "soon",
+						Namespace:  "ns",
+						Expiration: int64(30 * time.Second),
+					},
+					{
+						Name:       "expired",
+						Namespace:  "ns",
+						Expiration: 0,
+					},
+				}, nil)
+				clockMock.On("Now").Return(int64(100))
+				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
+					return a.Level == alert.Warn && a.Message == "Cert ns/soon expires in 29 seconds"
+				})).Return(nil).Once()
+				notifierMock.On("Send", mock.MatchedBy(func(a alert.Alert) bool {
+					return a.Level == alert.Error && a.Message == "certificate expired"
+				})).Return(nil).Once()
+			})
+			It("should render the message of the levels with a template", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				// other assertions are made on the notifier mock
+			})
+		})
+
+		When("notifier supports batches", func() {
+			var batchNotifierMock *mocks.BatchNotifier
+			BeforeEach(func() {
+				batchNotifierMock = &mocks.BatchNotifier{}
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, batchNotifierMock, nil, clockMock, monitor.Config{Threshold: threshold})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:       "expired",
+						Namespace:  "ns",
+						Expiration: 0,
+					},
+					{
+						Name:       "expiring",
+						Namespace:  "ns",
+						Expiration: int64(threshold),
+					},
+				}, nil)
+				clockMock.On("Now").Return(int64(100))
+			})
+			AfterEach(func() {
+				batchNotifierMock.AssertExpectations(GinkgoT())
+			})
+
+			When("alerts are sent", func() {
+				BeforeEach(func() {
+					batchNotifierMock.On("SendBatch", mock.MatchedBy(func(alerts []alert.Alert) bool {
+						Expect(alerts).Should(HaveLen(2))
+						Expect(alerts[0].Level).Should(Equal(alert.Error))
+						return Expect(alerts[1].Level).Should(Equal(alert.Warn))
+					})).Return(nil).Once()
+				})
+				It("should send all alerts at once", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					// other assertions are made on the notifier mock
+				})
+			})
+
+			When("batch failed", func() {
+				BeforeEach(func() {
+					batchNotifierMock.On("SendBatch", mock.Anything).Return(errors.New("broker is down")).Once()
+				})
+				It("should propagate the error", func() {
+					Expect(err).Should(MatchError("failed to send 2 alerts: broker is down"))
+				})
+			})
+		})
+
+		When("notifier is asynchronous", func() {
+			var asyncNotifierMock *mocks.AsyncNotifier
+			BeforeEach(func() {
+				asyncNotifierMock = &mocks.AsyncNotifier{}
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, asyncNotifierMock, nil, clockMock, monitor.Config{Threshold: threshold})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:       "cert-name",
+						Namespace:  "ns",
+						Expiration: 0,
+					},
+				}, nil)
+				clockMock.On("Now").Return(int64(100))
+				asyncNotifierMock.On("Send", mock.Anything).Return(nil).Once()
+			})
+			AfterEach(func() {
+				asyncNotifierMock.AssertExpectations(GinkgoT())
+			})
+
+			When("alerts are delivered", func() {
+				BeforeEach(func() {
+					asyncNotifierMock.On("Flush", mock.Anything).Return(nil).Once()
+				})
+				It("should wait for the delivery", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					// other assertions are made on the notifier mock
+				})
+			})
+
+			When("alerts failed to be delivered", func() {
+				BeforeEach(func() {
+					asyncNotifierMock.On("Flush", mock.Anything).Return(errors.New("broker is down")).Once()
+				})
+				It("should propagate the delivery failure", func() {
+					Expect(err).Should(MatchError("failed to deliver alerts: broker is down"))
+				})
+			})
+		})
+
+		When("flapping detection is enabled", func() {
+			const (
+				now    = int64(100)
+				window = 7 * 24 * time.Hour
+			)
+			var (
+				server            *httptest.Server
+				sql               string
+				rows              string
+				status            int
+				batchNotifierMock *mocks.BatchNotifier
+				sent              []alert.Alert
+			)
+			BeforeEach(func() {
+				status = http.StatusOK
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					body, _ := ioutil.ReadAll(r.Body)
+					sql = string(body)
+					w.WriteHeader(status)
+					fmt.Fprintf(w, `{"resultTable": {"dataSchema": {"columnNames": ["objectRef.namespace", "objectRef.name", "expiration"]}, "rows": %s}}`, rows)
+				}))
+				client, err := pinot.NewBrokerClient(pinot.Config{BrokerURL: server.URL})
+				Expect(err).ShouldNot(HaveOccurred())
+				batchNotifierMock = &mocks.BatchNotifier{}
+				m = monitor.NewCertificateMonitor(zap.S(), gathererMock, batchNotifierMock, pinot.NewAlertHistory(client, "certsAlerts", nil), clockMock, monitor.Config{
+					Threshold: threshold,
+					Flapping:  monitor.FlappingConfig{Window: window},
+				})
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
+					{
+						Name:       "flapping",
+						Namespace:  "ns",
+						Expiration: now + 10,
+					},
+					{
+						Name:       "stable",
+						Namespace:  "ns",
+						Expiration: now + 10,
+					},
+				}, nil)
+				clockMock.On("Now").Return(now)
+				batchNotifierMock.On("SendBatch", mock.MatchedBy(func(alerts []alert.Alert) bool {
+					sent = alerts
+					return true
+				})).Return(nil).Once()
+			})
+			AfterEach(func() {
+				server.Close()
+				batchNotifierMock.AssertExpectations(GinkgoT())
+			})
+
+			When("certificate went into WARN for several renewal cycles", func() {
+				BeforeEach(func() {
+					rows = `[["ns", "flapping", 10], ["ns", "flapping", 20], ["ns", "stable", 110]]`
+				})
+				It("should alert that the certificate is flapping", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(sql).Should(ContainSubstring(fmt.Sprint(now - window.Nanoseconds())))
+					Expect(sent).Should(HaveLen(3))
+					Expect(sent[2].Level).Should(Equal(alert.Error))
+					Expect(sent[2].ObjectRef.Name).Should(Equal("flapping"))
+					Expect(sent[2].Message).Should(Equal("certificate is flapping: 3 renewal cycles went into WARN or ERROR over the last 168h0m0s"))
+				})
+			})
+
+			When("alert history is unavailable", func() {
+				BeforeEach(func() {
+					status = http.StatusServiceUnavailable
+					rows = `[]`
+				})
+				It("should only send the certificate alerts", func() {
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(sent).Should(HaveLen(2))
+				})
+			})
+		})
+
 		When("failed to gather certificate info", func() {
 			var criticalErr = errors.New("endpoint is unreachable")
 			BeforeEach(func() {
-				gathererMock.On("GatherCertificateInfos").Return(nil, criticalErr)
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return(nil, criticalErr)
 			})
 			It("should propagate the error", func() {
 				Expect(err).Should(MatchError("failed to gather certificate information: endpoint is unreachable"))
@@ -146,7 +449,7 @@ var _ = Describe("Monitor", func() {
 		When("failed to send alerts", func() {
 			var criticalErr = errors.New("failed to connect to SMTP server")
 			BeforeEach(func() {
-				gathererMock.On("GatherCertificateInfos").Return([]monitor.CertificateInfo{
+				gathererMock.On("GatherCertificateInfos", mock.Anything).Return([]monitor.CertificateInfo{
 					{
 						Name:       "cert-name",
 						Namespace:  "ns",
@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package monitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+)
+
+// readX509Metadata completes the metadata with the serial number, the fingerprint, the key and the validity of the
+// first certificate of the PEM data, i.e. the leaf certificate of the chain stored by cert-manager
+func readX509Metadata(data []byte, metadata *alert.CertificateMetadata) error {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	fingerprint := sha256.Sum256(cert.Raw)
+	metadata.SerialNumber = hex.EncodeToString(cert.SerialNumber.Bytes())
+	metadata.FingerprintSHA256 = hex.EncodeToString(fingerprint[:])
+	metadata.NotBefore = cert.NotBefore.UnixNano()
+	metadata.NotAfter = cert.NotAfter.UnixNano()
+	if len(cert.DNSNames) > 0 {
+		metadata.DNSNames = cert.DNSNames
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		metadata.KeyAlgorithm = "RSA"
+		metadata.KeySize = key.N.BitLen()
+	case *ecdsa.PublicKey:
+		metadata.KeyAlgorithm = "ECDSA"
+		metadata.KeySize = key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		metadata.KeyAlgorithm = "Ed25519"
+		metadata.KeySize = len(key) * 8
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package monitor_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/mocks"
+	"github.com/dvergnes/pinot-playground/cert-monitor/monitor"
+
+	v1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("k8sCertificateInfoGatherer reading secrets and namespaces", func() {
+	var (
+		clientSetMock   *mocks.Interface
+		certManagerMock *mocks.CertmanagerV1Interface
+		certAPIMock     *mocks.CertificateInterface
+		server          *httptest.Server
+		paths           []string
+		secretData      map[string][]byte
+
+		certs []monitor.CertificateInfo
+		err   error
+
+		der       []byte
+		notBefore = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		notAfter  = time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ShouldNot(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(0x1234),
+			Subject:      pkix.Name{CommonName: "example.com"},
+			DNSNames:     []string{"example.com", "www.example.com"},
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+		}
+		der, err = x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).ShouldNot(HaveOccurred())
+		secretData = map[string][]byte{
+			corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		}
+
+		paths = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v1/namespaces/ns/secrets":
+				Expect(r.URL.Query().Get("fieldSelector")).Should(Equal("type=kubernetes.io/tls"))
+				_ = json.NewEncoder(w).Encode(corev1.SecretList{
+					TypeMeta: metav1.TypeMeta{Kind: "SecretList", APIVersion: "v1"},
+					Items: []corev1.Secret{{
+						ObjectMeta: metav1.ObjectMeta{Name: "cert-tls", Namespace: "ns"},
+						Type:       corev1.SecretTypeTLS,
+						Data:       secretData,
+					}},
+				})
+			case "/api/v1/namespaces/ns":
+				_ = json.NewEncoder(w).Encode(corev1.Namespace{
+					TypeMeta: metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "ns",
+						Labels:      map[string]string{"team": "payments"},
+						Annotations: map[string]string{"owner": "payments@example.com"},
+					},
+				})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		k8sClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		clientSetMock = &mocks.Interface{}
+		certManagerMock = &mocks.CertmanagerV1Interface{}
+		certAPIMock = &mocks.CertificateInterface{}
+		clientSetMock.On("CertmanagerV1").Return(certManagerMock)
+		certManagerMock.On("Certificates", "").Return(certAPIMock)
+		certAPIMock.On("List", mock.Anything, mock.Anything).Return(&v1.CertificateList{
+			Items: []v1.Certificate{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "cert", Namespace: "ns"},
+					Spec: v1.CertificateSpec{
+						SecretName: "cert-tls",
+						PrivateKey: &v1.CertificatePrivateKey{Algorithm: v1.RSAKeyAlgorithm, Size: 2048},
+					},
+					Status: v1.CertificateStatus{NotAfter: &metav1.Time{Time: notAfter}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"},
+					Spec:       v1.CertificateSpec{SecretName: "other-tls"},
+					Status:     v1.CertificateStatus{NotAfter: &metav1.Time{Time: notAfter}},
+				},
+			},
+		}, nil)
+
+		gatherer := monitor.NewKubernetesCertificateInfoGatherer(zap.S(), clientSetMock, k8sClient.CoreV1(), k8sClient.CoreV1(), monitor.GathererConfig{
+			PageSize: 10,
+			Timeout:  time.Second,
+		})
+		certs, err = gatherer.GatherCertificateInfos(context.Background())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should read the metadata of the X.509 certificate", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(paths).Should(ContainElement("/api/v1/namespaces/ns/secrets"))
+		fingerprint := sha256.Sum256(der)
+		Expect(certs).Should(HaveLen(2))
+		Expect(certs[0].Metadata).Should(Equal(alert.CertificateMetadata{
+			IssuerKind:        "Issuer",
+			SecretName:        "cert-tls",
+			DNSNames:          []string{"example.com", "www.example.com"},
+			SerialNumber:      "1234",
+			FingerprintSHA256: hex.EncodeToString(fingerprint[:]),
+			KeyAlgorithm:      "ECDSA",
+			KeySize:           384,
+			NotBefore:         notBefore.UnixNano(),
+			NotAfter:          notAfter.UnixNano(),
+		}))
+	})
+
+	It("should keep the metadata of the spec when the secret is not found", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(certs).Should(HaveLen(2))
+		Expect(certs[1].Metadata).Should(Equal(alert.CertificateMetadata{
+			IssuerKind: "Issuer",
+			SecretName: "other-tls",
+			NotAfter:   notAfter.UnixNano(),
+		}))
+	})
+
+	It("should list the secrets and read the namespaces once per namespace", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(certs).Should(HaveLen(2))
+		for _, cert := range certs {
+			Expect(cert.NamespaceLabels).Should(Equal(map[string]string{"team": "payments"}))
+			Expect(cert.NamespaceAnnotations).Should(Equal(map[string]string{"owner": "payments@example.com"}))
+		}
+		Expect(paths).Should(Equal([]string{"/api/v1/namespaces/ns/secrets", "/api/v1/namespaces/ns"}))
+	})
+})
@@ -0,0 +1,247 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Action is the action taken by the bootstrap on a Pinot resource
+type Action string
+
+const (
+	// ActionCreate means that the resource does not exist and is created
+	ActionCreate Action = "create"
+	// ActionUpdate means that the resource differs from the desired one and is updated
+	ActionUpdate Action = "update"
+	// ActionNone means that the resource already matches the desired one
+	ActionNone Action = "none"
+)
+
+// Change is a difference between the current and the desired value at a path of a resource
+type Change struct {
+	// Path locates the value in the resource, e.g. segmentsConfig.replication or dimensionFieldSpecs[level]
+	Path string
+	// Current is the current value, nil when it is missing
+	Current interface{}
+	// Desired is the desired value
+	Desired interface{}
+}
+
+// String returns the change as `path: current -> desired` with the values in JSON
+func (c Change) String() string {
+	current := "<none>"
+	if c.Current != nil {
+		current = toJSON(c.Current)
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Path, current, toJSON(c.Desired))
+}
+
+// Result reports the action taken on a resource
+type Result struct {
+	// Kind is the kind of the resource, schema or table
+	Kind string
+	// Name is the name of the resource
+	Name string
+	// Action is the action taken on the resource
+	Action Action
+	// Changes contains the differences between the current and the desired resource, it is empty when the resource is
+	// created
+	Changes []Change
+}
+
+// immutablePaths are the paths of the table config that Pinot does not support to update, e.g. the consuming segments
+// of a high level consumer are not migrated to a low level one
+var immutablePaths = map[string]struct{}{
+	"tableIndexConfig.streamConfigs.stream.kafka.consumer.type": {},
+}
+
+// Bootstrap creates the schema and the tables when they do not exist and updates them when they differ from the
+// desired ones, so it can be run repeatedly. The values set by Pinot on top of the desired ones are not considered as
+// differences. Nothing is applied in dry run, the results report what would be done. The tables whose immutable values
+// differ are not updated, an error is returned instead.
+func Bootstrap(ctx context.Context, controller *Controller, schema Schema, tables []TableConfig, dryRun bool) ([]Result, error) {
+	current, err := controller.Schema(ctx, schema.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+	schemaResult, err := plan("schema", schema.SchemaName, schema, current)
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{schemaResult}
+	for _, table := range tables {
+		current, err = controller.TableConfig(ctx, table.TableName, table.TableType)
+		if err != nil {
+			return nil, err
+		}
+		tableResult, err := plan("table", table.TableName, table, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range tableResult.Changes {
+			if _, found := immutablePaths[change.Path]; found && change.Current != nil {
+				return nil, fmt.Errorf("failed to update table %s: %s cannot be changed in place, delete the table first", table.TableName, change)
+			}
+		}
+		results = append(results, tableResult)
+	}
+	if dryRun {
+		return results, nil
+	}
+
+	// the schema is applied first as the tables refer to it
+	switch schemaResult.Action {
+	case ActionCreate:
+		err = controller.CreateSchema(ctx, schema)
+	case ActionUpdate:
+		err = controller.UpdateSchema(ctx, schema)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, table := range tables {
+		switch results[i+1].Action {
+		case ActionCreate:
+			err = controller.CreateTable(ctx, table)
+		case ActionUpdate:
+			err = controller.UpdateTable(ctx, table)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// plan compares the desired resource with the current one, nil when it does not exist
+func plan(kind, name string, desired interface{}, current map[string]interface{}) (Result, error) {
+	result := Result{Kind: kind, Name: name, Action: ActionCreate}
+	if current == nil {
+		return result, nil
+	}
+	generic, err := toGeneric(desired)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to convert %s %s: %w", kind, name, err)
+	}
+	result.Changes = diff("", generic, current, nil)
+	result.Action = ActionUpdate
+	if len(result.Changes) == 0 {
+		result.Action = ActionNone
+	}
+	return result, nil
+}
+
+// diff returns the changes needed for current to contain desired, the values of current not in desired are ignored.
+// The elements of the arrays having a name are matched by name, e.g. the field specs of a schema.
+func diff(path string, desired, current interface{}, changes []Change) []Change {
+	switch desired := desired.(type) {
+	case map[string]interface{}:
+		currentMap, _ := current.(map[string]interface{})
+		keys := make([]string, 0, len(desired))
+		for key := range desired {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			changes = diff(joinPath(path, key), desired[key], currentMap[key], changes)
+		}
+	case []interface{}:
+		currentArray, _ := current.([]interface{})
+		if !namedElements(desired) {
+			if !reflect.DeepEqual(desired, currentArray) {
+				changes = append(changes, Change{Path: path, Current: current, Desired: desired})
+			}
+			return changes
+		}
+		byName := map[string]interface{}{}
+		for _, element := range currentArray {
+			if name, ok := elementName(element); ok {
+				byName[name] = element
+			}
+		}
+		for _, element := range desired {
+			name, _ := elementName(element)
+			elementPath := fmt.Sprintf("%s[%s]", path, name)
+			currentElement, found := byName[name]
+			if !found {
+				changes = append(changes, Change{Path: elementPath, Desired: element})
+				continue
+			}
+			changes = diff(elementPath, element, currentElement, changes)
+		}
+	default:
+		// Pinot may return the numbers as strings and vice versa
+		if current == nil || fmt.Sprint(desired) != fmt.Sprint(current) {
+			changes = append(changes, Change{Path: path, Current: current, Desired: desired})
+		}
+	}
+	return changes
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func namedElements(elements []interface{}) bool {
+	for _, element := range elements {
+		if _, ok := elementName(element); !ok {
+			return false
+		}
+	}
+	return len(elements) > 0
+}
+
+func elementName(element interface{}) (string, bool) {
+	object, ok := element.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	name, ok := object["name"].(string)
+	return name, ok
+}
+
+// toGeneric converts the value to its generic JSON representation
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
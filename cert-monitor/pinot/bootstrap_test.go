@@ -0,0 +1,227 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeController stores the schemas and the tables like the Pinot controller
+type fakeController struct {
+	mu       sync.Mutex
+	schemas  map[string]map[string]interface{}
+	tables   map[string]map[string]interface{}
+	requests []string
+}
+
+func (f *fakeController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	var body map[string]interface{}
+	if r.Method != http.MethodGet {
+		data, _ := ioutil.ReadAll(r.Body)
+		Expect(json.Unmarshal(data, &body)).Should(Succeed())
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/schemas/certsAlerts":
+		if schema, ok := f.schemas["certsAlerts"]; ok {
+			_ = json.NewEncoder(w).Encode(schema)
+			return
+		}
+		http.Error(w, `{"code":404,"error":"Schema certsAlerts not found"}`, http.StatusNotFound)
+	case r.Method == http.MethodPost && r.URL.Path == "/schemas",
+		r.Method == http.MethodPut && r.URL.Path == "/schemas/certsAlerts":
+		f.schemas[body["schemaName"].(string)] = body
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/tables/certsAlerts_"):
+		tableType := strings.ToUpper(r.URL.Query().Get("type"))
+		Expect(r.URL.Path).Should(Equal("/tables/certsAlerts_" + tableType))
+		tables := map[string]interface{}{}
+		if table, ok := f.tables["certsAlerts_"+tableType]; ok {
+			tables[tableType] = table
+		}
+		_ = json.NewEncoder(w).Encode(tables)
+	case r.Method == http.MethodPost && r.URL.Path == "/tables",
+		r.Method == http.MethodPut && r.URL.Path == "/tables/certsAlerts":
+		f.tables[body["tableName"].(string)] = body
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+var _ = Describe("Bootstrap", func() {
+	var (
+		fake   *fakeController
+		server *httptest.Server
+
+		controller *pinot.Controller
+		schema     pinot.Schema
+		tables     []pinot.TableConfig
+		dryRun     bool
+		results    []pinot.Result
+		err        error
+	)
+
+	BeforeEach(func() {
+		fake = &fakeController{
+			schemas: map[string]map[string]interface{}{},
+			tables:  map[string]map[string]interface{}{},
+		}
+		server = httptest.NewServer(fake)
+		dryRun = false
+
+		cfg := pinot.Config{ControllerURL: server.URL + "/"}
+		controller, err = pinot.NewController(cfg)
+		Expect(err).ShouldNot(HaveOccurred())
+		schema, err = pinot.AlertSchema(pinot.DefaultTable)
+		Expect(err).ShouldNot(HaveOccurred())
+		realtime, err := pinot.RealtimeTableConfig(cfg, alert.KafkaConfig{Topic: "alerts", Brokers: []string{"kafka:9092"}}, schema)
+		Expect(err).ShouldNot(HaveOccurred())
+		offline, err := pinot.OfflineTableConfig(cfg, schema)
+		Expect(err).ShouldNot(HaveOccurred())
+		tables = []pinot.TableConfig{realtime, offline}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		fake.requests = nil
+		results, err = pinot.Bootstrap(context.Background(), controller, schema, tables, dryRun)
+	})
+
+	When("schema and table do not exist", func() {
+		It("should create them", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(results).Should(Equal([]pinot.Result{
+				{Kind: "schema", Name: "certsAlerts", Action: pinot.ActionCreate},
+				{Kind: "table", Name: "certsAlerts_REALTIME", Action: pinot.ActionCreate},
+				{Kind: "table", Name: "certsAlerts_OFFLINE", Action: pinot.ActionCreate},
+			}))
+			Expect(fake.requests).Should(ContainElements("POST /schemas", "POST /tables"))
+			Expect(fake.schemas).Should(HaveKey("certsAlerts"))
+			Expect(fake.tables).Should(HaveKey("certsAlerts_REALTIME"))
+			Expect(fake.tables).Should(HaveKey("certsAlerts_OFFLINE"))
+		})
+
+		When("dry run", func() {
+			BeforeEach(func() {
+				dryRun = true
+			})
+
+			It("should only report the creations", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(results[0].Action).Should(Equal(pinot.ActionCreate))
+				Expect(results[1].Action).Should(Equal(pinot.ActionCreate))
+				Expect(results[2].Action).Should(Equal(pinot.ActionCreate))
+				Expect(fake.schemas).Should(BeEmpty())
+				Expect(fake.tables).Should(BeEmpty())
+			})
+		})
+	})
+
+	When("schema and table are up to date", func() {
+		BeforeEach(func() {
+			_, err = pinot.Bootstrap(context.Background(), controller, schema, tables, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			// Pinot adds its defaults to the stored resources
+			fake.tables["certsAlerts_REALTIME"]["metadata"] = map[string]interface{}{"customConfigs": map[string]interface{}{}}
+		})
+
+		It("should not change anything", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(results).Should(Equal([]pinot.Result{
+				{Kind: "schema", Name: "certsAlerts", Action: pinot.ActionNone},
+				{Kind: "table", Name: "certsAlerts_REALTIME", Action: pinot.ActionNone},
+				{Kind: "table", Name: "certsAlerts_OFFLINE", Action: pinot.ActionNone},
+			}))
+			Expect(fake.requests).Should(Equal([]string{"GET /schemas/certsAlerts", "GET /tables/certsAlerts_REALTIME", "GET /tables/certsAlerts_OFFLINE"}))
+		})
+	})
+
+	When("schema and table differ", func() {
+		BeforeEach(func() {
+			_, err = pinot.Bootstrap(context.Background(), controller, schema, tables, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			var fields []interface{}
+			for _, field := range fake.schemas["certsAlerts"]["dimensionFieldSpecs"].([]interface{}) {
+				if field.(map[string]interface{})["name"] != "source" {
+					fields = append(fields, field)
+				}
+			}
+			fake.schemas["certsAlerts"]["dimensionFieldSpecs"] = fields
+			fake.tables["certsAlerts_REALTIME"]["segmentsConfig"].(map[string]interface{})["replication"] = "2"
+		})
+
+		It("should update them", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(results).Should(HaveLen(3))
+			Expect(results[0].Action).Should(Equal(pinot.ActionUpdate))
+			Expect(results[0].Changes).Should(HaveLen(1))
+			Expect(results[0].Changes[0].String()).Should(Equal(`dimensionFieldSpecs[source]: <none> -> {"dataType":"STRING","name":"source"}`))
+			Expect(results[1].Action).Should(Equal(pinot.ActionUpdate))
+			Expect(results[1].Changes).Should(HaveLen(1))
+			Expect(results[1].Changes[0].String()).Should(Equal(`segmentsConfig.replication: "2" -> "1"`))
+			Expect(results[2].Action).Should(Equal(pinot.ActionNone))
+			Expect(fake.requests).Should(ContainElements("PUT /schemas/certsAlerts", "PUT /tables/certsAlerts"))
+			Expect(fake.schemas["certsAlerts"]["dimensionFieldSpecs"]).Should(HaveLen(len(schema.DimensionFieldSpecs)))
+		})
+	})
+
+	When("table consumes the stream with another consumer type", func() {
+		BeforeEach(func() {
+			_, err = pinot.Bootstrap(context.Background(), controller, schema, tables, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			streamConfigs := fake.tables["certsAlerts_REALTIME"]["tableIndexConfig"].(map[string]interface{})["streamConfigs"]
+			streamConfigs.(map[string]interface{})["stream.kafka.consumer.type"] = "simple"
+			fake.tables["certsAlerts_REALTIME"]["segmentsConfig"].(map[string]interface{})["replication"] = "2"
+		})
+
+		It("should refuse to update it", func() {
+			Expect(err).Should(MatchError(`failed to update table certsAlerts_REALTIME: tableIndexConfig.streamConfigs.stream.kafka.consumer.type: "simple" -> "lowlevel" cannot be changed in place, delete the table first`))
+			Expect(fake.requests).ShouldNot(ContainElement("PUT /tables/certsAlerts"))
+			Expect(fake.tables["certsAlerts_REALTIME"]["segmentsConfig"].(map[string]interface{})["replication"]).Should(Equal("2"))
+		})
+	})
+
+	When("controller fails", func() {
+		BeforeEach(func() {
+			server.Close()
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError(ContainSubstring("failed to get schema certsAlerts")))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ResultTable contains the result of a SQL query
+type ResultTable struct {
+	// DataSchema describes the columns of the rows
+	DataSchema DataSchema `json:"dataSchema"`
+	// Rows contains the values of the rows, the numbers are decoded as json.Number to keep the precision of the LONG
+	// values
+	Rows [][]interface{} `json:"rows"`
+}
+
+// DataSchema describes the columns of a result table
+type DataSchema struct {
+	// ColumnNames contains the names of the columns
+	ColumnNames []string `json:"columnNames"`
+	// ColumnDataTypes contains the Pinot data types of the columns
+	ColumnDataTypes []string `json:"columnDataTypes"`
+}
+
+// QueryClient runs SQL queries on Pinot
+type QueryClient interface {
+	// Query runs the SQL query and returns its result
+	Query(ctx context.Context, sql string) (ResultTable, error)
+}
+
+// NewBrokerClient returns a QueryClient sending the queries to the SQL endpoint of the broker of the configuration
+func NewBrokerClient(cfg Config) (QueryClient, error) {
+	cfg = cfg.WithDefaults()
+	if cfg.BrokerURL == "" {
+		return nil, errors.New("missing Pinot broker URL")
+	}
+	return &brokerClient{
+		url:    strings.TrimSuffix(cfg.BrokerURL, "/") + "/query/sql",
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+type brokerClient struct {
+	url    string
+	client *http.Client
+}
+
+type queryResponse struct {
+	ResultTable ResultTable      `json:"resultTable"`
+	Exceptions  []queryException `json:"exceptions"`
+}
+
+type queryException struct {
+	ErrorCode int    `json:"errorCode"`
+	Message   string `json:"message"`
+}
+
+func (b *brokerClient) Query(ctx context.Context, sql string) (ResultTable, error) {
+	data, err := json.Marshal(map[string]string{"sql": sql})
+	if err != nil {
+		return ResultTable{}, fmt.Errorf("failed to marshal query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(data))
+	if err != nil {
+		return ResultTable{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	var resp queryResponse
+	if err := doRequest(b.client, req, &resp); err != nil {
+		return ResultTable{}, fmt.Errorf("failed to query Pinot: %w", err)
+	}
+	// the broker reports the query errors in the body of a 200 response
+	if len(resp.Exceptions) > 0 {
+		messages := make([]string, 0, len(resp.Exceptions))
+		for _, exception := range resp.Exceptions {
+			messages = append(messages, fmt.Sprintf("%d: %s", exception.ErrorCode, exception.Message))
+		}
+		return ResultTable{}, fmt.Errorf("failed to query Pinot: %s", strings.Join(messages, "; "))
+	}
+	return resp.ResultTable, nil
+}
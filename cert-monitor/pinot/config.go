@@ -0,0 +1,77 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/internal/validation"
+)
+
+const (
+	// DefaultTable is the name of the table and of the schema of the alerts when none is configured
+	DefaultTable = "certsAlerts"
+	// DefaultTimeout is the timeout of the calls to Pinot when none is configured
+	DefaultTimeout = 10 * time.Second
+	// DefaultRetentionDays is the number of days the segments of the alerts are kept when none is configured
+	DefaultRetentionDays = 10
+)
+
+// Config contains the configuration to reach the Pinot cluster storing the alerts
+type Config struct {
+	// ControllerURL is the base URL of the Pinot controller
+	ControllerURL string `yaml:"controller_url"`
+	// BrokerURL is the base URL of the Pinot broker
+	BrokerURL string `yaml:"broker_url"`
+	// Table is the name of the table and of the schema of the alerts, DefaultTable when not set
+	Table string `yaml:"table"`
+	// Timeout defines the timeout of the calls to Pinot, DefaultTimeout when not set
+	Timeout time.Duration `yaml:"timeout"`
+	// Replication is the number of replicas of the segments, 1 when not set
+	Replication int `yaml:"replication"`
+	// RetentionDays is the number of days the segments are kept, DefaultRetentionDays when not set
+	RetentionDays int `yaml:"retention_days"`
+}
+
+// WithDefaults returns the configuration with the defaults applied to the fields not set
+func (c Config) WithDefaults() Config {
+	if c.Table == "" {
+		c.Table = DefaultTable
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Replication <= 0 {
+		c.Replication = 1
+	}
+	if c.RetentionDays <= 0 {
+		c.RetentionDays = DefaultRetentionDays
+	}
+	return c
+}
+
+// Validate adds the violations of the configuration to v, the URLs are only required by the features using them
+func (c Config) Validate(v *validation.Validator) {
+	v.URL("controller_url", c.ControllerURL)
+	v.URL("broker_url", c.BrokerURL)
+	v.NotNegativeDuration("timeout", c.Timeout)
+	v.NotNegative("replication", int64(c.Replication))
+	v.NotNegative("retention_days", int64(c.RetentionDays))
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxErrorBodySize is the maximum number of bytes of the response body reported in the errors
+const maxErrorBodySize = 512
+
+// errNotFound is returned when the requested resource does not exist
+var errNotFound = errors.New("not found")
+
+// Controller manages the schemas and the tables with the REST API of the Pinot controller
+type Controller struct {
+	url    string
+	client *http.Client
+}
+
+// NewController returns a Controller calling the controller of the configuration
+func NewController(cfg Config) (*Controller, error) {
+	cfg = cfg.WithDefaults()
+	if cfg.ControllerURL == "" {
+		return nil, errors.New("missing Pinot controller URL")
+	}
+	return &Controller{
+		url:    strings.TrimSuffix(cfg.ControllerURL, "/"),
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Schema returns the schema with the given name as generic JSON, nil when it does not exist
+func (c *Controller) Schema(ctx context.Context, name string) (map[string]interface{}, error) {
+	var schema map[string]interface{}
+	err := c.do(ctx, http.MethodGet, "/schemas/"+url.PathEscape(name), nil, &schema)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema %s: %w", name, err)
+	}
+	return schema, nil
+}
+
+// CreateSchema creates the schema
+func (c *Controller) CreateSchema(ctx context.Context, schema Schema) error {
+	if err := c.do(ctx, http.MethodPost, "/schemas", schema, nil); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema.SchemaName, err)
+	}
+	return nil
+}
+
+// UpdateSchema updates the existing schema
+func (c *Controller) UpdateSchema(ctx context.Context, schema Schema) error {
+	if err := c.do(ctx, http.MethodPut, "/schemas/"+url.PathEscape(schema.SchemaName), schema, nil); err != nil {
+		return fmt.Errorf("failed to update schema %s: %w", schema.SchemaName, err)
+	}
+	return nil
+}
+
+// TableConfig returns the configuration of the table with the given name and type as generic JSON, nil when it does not
+// exist
+func (c *Controller) TableConfig(ctx context.Context, name, tableType string) (map[string]interface{}, error) {
+	var tables map[string]map[string]interface{}
+	err := c.do(ctx, http.MethodGet, "/tables/"+url.PathEscape(name)+"?type="+strings.ToLower(tableType), nil, &tables)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table %s: %w", name, err)
+	}
+	// the configuration is keyed by the type of the table, the response is empty when the table does not exist
+	return tables[strings.ToUpper(tableType)], nil
+}
+
+// CreateTable creates the table
+func (c *Controller) CreateTable(ctx context.Context, table TableConfig) error {
+	if err := c.do(ctx, http.MethodPost, "/tables", table, nil); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", table.TableName, err)
+	}
+	return nil
+}
+
+// UpdateTable updates the existing table
+func (c *Controller) UpdateTable(ctx context.Context, table TableConfig) error {
+	name := strings.TrimSuffix(table.TableName, "_"+table.TableType)
+	if err := c.do(ctx, http.MethodPut, "/tables/"+url.PathEscape(name), table, nil); err != nil {
+		return fmt.Errorf("failed to update table %s: %w", table.TableName, err)
+	}
+	return nil
+}
+
+// do sends the request with the body encoded in JSON and decodes the JSON response in out when set. errNotFound is
+// returned when the status is 404.
+func (c *Controller) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return doRequest(c.client, req, out)
+}
+
+// doRequest sends the request and decodes the JSON response in out when set, the numbers are decoded as json.Number. An
+// error is returned when the status is not 2xx with the beginning of the response body.
+func doRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+		msg := strings.TrimSpace(string(body))
+		if len(body) > maxErrorBodySize {
+			msg = strings.TrimSpace(string(body[:maxErrorBodySize])) + "..."
+		}
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+)
+
+const (
+	// DefaultHistoryLimit is the maximum number of alerts returned by Alerts when no limit is set
+	DefaultHistoryLimit = 100
+	// maxProblemCycles is the maximum number of renewal cycles returned by ProblemCycles
+	maxProblemCycles = 10000
+)
+
+// HistoryQuery selects alerts from the history, the fields not set match all the alerts
+type HistoryQuery struct {
+	// Name is the name of the certificate
+	Name string
+	// Namespace is the namespace of the certificate
+	Namespace string
+	// Levels contains the levels of the alerts
+	Levels []alert.Level
+	// From is the beginning of the time range, inclusive
+	From time.Time
+	// To is the end of the time range, exclusive
+	To time.Time
+	// Limit is the maximum number of alerts returned, DefaultHistoryLimit when not set
+	Limit int
+}
+
+// AlertHistory queries the alerts stored in a Pinot table
+type AlertHistory struct {
+	client QueryClient
+	table  string
+	// rename maps the paths of the alert fields to the columns, see alert.WireFormatConfig
+	rename map[string]string
+}
+
+// NewAlertHistory returns an AlertHistory querying the table with the client. The columns are named after the paths of
+// the flattened alert fields, e.g. objectRef.name, unless they are renamed by rename.
+func NewAlertHistory(client QueryClient, table string, rename map[string]string) *AlertHistory {
+	return &AlertHistory{
+		client: client,
+		table:  table,
+		rename: rename,
+	}
+}
+
+// column returns the quoted column of the alert field
+func (h *AlertHistory) column(path string) string {
+	if to, found := h.rename[path]; found {
+		path = to
+	}
+	return `"` + path + `"`
+}
+
+// Alerts returns the alerts matching the query, the most recent first
+func (h *AlertHistory) Alerts(ctx context.Context, query HistoryQuery) ([]alert.Alert, error) {
+	result, err := h.client.Query(ctx, h.historySQL(query))
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]alert.Alert, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		a, err := h.decodeAlert(result.DataSchema, row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+func (h *AlertHistory) historySQL(query HistoryQuery) string {
+	var conditions []string
+	if query.Name != "" {
+		conditions = append(conditions, h.column("objectRef.name")+" = "+quote(query.Name))
+	}
+	if query.Namespace != "" {
+		conditions = append(conditions, h.column("objectRef.namespace")+" = "+quote(query.Namespace))
+	}
+	if len(query.Levels) > 0 {
+		levels := make([]string, 0, len(query.Levels))
+		for _, level := range query.Levels {
+			levels = append(levels, quote(level.String()))
+		}
+		conditions = append(conditions, h.column("level")+" IN ("+strings.Join(levels, ", ")+")")
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("%s >= %d", h.column("when"), query.From.UnixNano()))
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("%s < %d", h.column("when"), query.To.UnixNano()))
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	sql := "SELECT * FROM " + h.table
+	if len(conditions) > 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return fmt.Sprintf("%s ORDER BY %s DESC LIMIT %d", sql, h.column("when"), limit)
+}
+
+// ProblemCycle identifies a renewal cycle of a certificate, i.e. an expiration, which went into WARN or ERROR
+type ProblemCycle struct {
+	// Namespace is the namespace of the certificate
+	Namespace string
+	// Name is the name of the certificate
+	Name string
+	// Expiration is the expiration of the certificate in nanoseconds since the epoch
+	Expiration int64
+}
+
+// ProblemCycles returns the renewal cycles of the certificates with WARN or ERROR alerts since the given time
+func (h *AlertHistory) ProblemCycles(ctx context.Context, since time.Time) ([]ProblemCycle, error) {
+	columns := strings.Join([]string{
+		h.column("objectRef.namespace"), h.column("objectRef.name"), h.column("expiration"),
+	}, ", ")
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN ('WARN', 'ERROR') AND %s >= %d GROUP BY %s LIMIT %d",
+		columns, h.table, h.column("level"), h.column("when"), since.UnixNano(), columns, maxProblemCycles)
+	result, err := h.client.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	cycles := make([]ProblemCycle, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		if len(row) != 3 {
+			return nil, fmt.Errorf("failed to decode problem cycle: expected 3 values, got %d", len(row))
+		}
+		namespace, _ := row[0].(string)
+		name, _ := row[1].(string)
+		expiration, err := toInt64(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode problem cycle: %w", err)
+		}
+		cycles = append(cycles, ProblemCycle{Namespace: namespace, Name: name, Expiration: expiration})
+	}
+	return cycles, nil
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Int64()
+	case string:
+		// the aggregations may return the LONG values as strings
+		return json.Number(v).Int64()
+	default:
+		return 0, fmt.Errorf("unexpected value %v", v)
+	}
+}
+
+// quote returns the string as a SQL literal
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// isNull returns true when the value is the default null value stored by Pinot in the dimension of the given type
+// when the field is missing
+func isNull(dataType string, value interface{}) bool {
+	switch dataType {
+	case "STRING":
+		return value == "null"
+	case "STRING_ARRAY":
+		values, ok := value.([]interface{})
+		return ok && len(values) == 1 && values[0] == "null"
+	case "INT":
+		return fmt.Sprint(value) == strconv.Itoa(math.MinInt32)
+	case "LONG":
+		return fmt.Sprint(value) == strconv.FormatInt(math.MinInt64, 10)
+	default:
+		return false
+	}
+}
+
+// decodeAlert decodes a row of the alerts table, the renamed columns are mapped back to their alert field and the
+// flattened columns like objectRef.name are nested back
+func (h *AlertHistory) decodeAlert(schema DataSchema, row []interface{}) (alert.Alert, error) {
+	if len(schema.ColumnNames) != len(row) {
+		return alert.Alert{}, fmt.Errorf("expected %d values, got %d", len(schema.ColumnNames), len(row))
+	}
+	paths := make(map[string]string, len(h.rename))
+	for from, to := range h.rename {
+		paths[to] = from
+	}
+	record := map[string]interface{}{}
+	for i, column := range schema.ColumnNames {
+		if i < len(schema.ColumnDataTypes) && isNull(schema.ColumnDataTypes[i], row[i]) {
+			continue
+		}
+		if path, found := paths[column]; found {
+			column = path
+		}
+		fields := strings.Split(column, ".")
+		parent := record
+		for _, field := range fields[:len(fields)-1] {
+			child, ok := parent[field].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				parent[field] = child
+			}
+			parent = child
+		}
+		parent[fields[len(fields)-1]] = row[i]
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return alert.Alert{}, err
+	}
+	var a alert.Alert
+	if err := json.Unmarshal(data, &a); err != nil {
+		return alert.Alert{}, err
+	}
+	return a, nil
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History", func() {
+	var (
+		server   *httptest.Server
+		path     string
+		sql      string
+		response string
+
+		query  pinot.HistoryQuery
+		rename map[string]string
+		alerts []alert.Alert
+		err    error
+
+		now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			body, _ := ioutil.ReadAll(r.Body)
+			var req map[string]string
+			Expect(json.Unmarshal(body, &req)).Should(Succeed())
+			sql = req["sql"]
+			_, _ = w.Write([]byte(response))
+		}))
+		response = `{
+			"resultTable": {
+				"dataSchema": {
+					"columnNames": ["certificate.dnsNames", "certificate.issuerName", "certificate.keySize", "expiration", "level", "message", "objectRef.kind", "objectRef.name", "objectRef.namespace", "source", "when"],
+					"columnDataTypes": ["STRING_ARRAY", "STRING", "LONG", "LONG", "STRING", "STRING", "STRING", "STRING", "STRING", "STRING", "LONG"]
+				},
+				"rows": [
+					[["example.com"], "ca", 2048, 1640998800000000001, "ERROR", "certificate expired", "null", "cert", "ns", "pod", 1640995200000000001],
+					[["null"], "null", -9223372036854775808, 1640998800000000001, "WARN", "certificate is about to expire", "null", "cert", "ns", "pod", 1640995100000000001]
+				]
+			},
+			"exceptions": []
+		}`
+		rename = nil
+		query = pinot.HistoryQuery{
+			Name:      "cert",
+			Namespace: "o'brien",
+			Levels:    []alert.Level{alert.Warn, alert.Error},
+			From:      now.Add(-time.Hour),
+			To:        now,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		var client pinot.QueryClient
+		client, err = pinot.NewBrokerClient(pinot.Config{BrokerURL: server.URL + "/"})
+		Expect(err).ShouldNot(HaveOccurred())
+		alerts, err = pinot.NewAlertHistory(client, "certsAlerts", rename).Alerts(context.Background(), query)
+	})
+
+	It("should query the alerts matching the filters", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(path).Should(Equal("/query/sql"))
+		Expect(sql).Should(Equal(`SELECT * FROM certsAlerts WHERE "objectRef.name" = 'cert' AND "objectRef.namespace" = 'o''brien' ` +
+			`AND "level" IN ('WARN', 'ERROR') AND "when" >= 1640991600000000000 AND "when" < 1640995200000000000 ` +
+			`ORDER BY "when" DESC LIMIT 100`))
+	})
+
+	It("should decode the alerts", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(alerts).Should(Equal([]alert.Alert{
+			{
+				Level:      alert.Error,
+				Message:    "certificate expired",
+				ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+				Source:     "pod",
+				When:       1640995200000000001,
+				Expiration: 1640998800000000001,
+				Certificate: &alert.CertificateMetadata{
+					IssuerName: "ca",
+					DNSNames:   []string{"example.com"},
+					KeySize:    2048,
+				},
+			},
+			{
+				Level:      alert.Warn,
+				Message:    "certificate is about to expire",
+				ObjectRef:  alert.ObjectRef{Name: "cert", Namespace: "ns"},
+				Source:     "pod",
+				When:       1640995100000000001,
+				Expiration: 1640998800000000001,
+			},
+		}))
+	})
+
+	When("no filter is set", func() {
+		BeforeEach(func() {
+			query = pinot.HistoryQuery{Limit: 10}
+		})
+
+		It("should query all the alerts", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sql).Should(Equal(`SELECT * FROM certsAlerts ORDER BY "when" DESC LIMIT 10`))
+		})
+	})
+
+	When("columns are renamed", func() {
+		BeforeEach(func() {
+			rename = map[string]string{"objectRef.name": "certificate", "when": "timestamp"}
+			query = pinot.HistoryQuery{Name: "cert"}
+			response = `{
+				"resultTable": {
+					"dataSchema": {
+						"columnNames": ["certificate", "cluster", "level", "timestamp"],
+						"columnDataTypes": ["STRING", "STRING", "STRING", "LONG"]
+					},
+					"rows": [["cert", "prod", "WARN", 1640995200000000001]]
+				}
+			}`
+		})
+
+		It("should query and decode the renamed columns", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sql).Should(Equal(`SELECT * FROM certsAlerts WHERE "certificate" = 'cert' ORDER BY "timestamp" DESC LIMIT 100`))
+			Expect(alerts).Should(Equal([]alert.Alert{
+				{
+					Level:     alert.Warn,
+					ObjectRef: alert.ObjectRef{Name: "cert"},
+					When:      1640995200000000001,
+				},
+			}))
+		})
+	})
+
+	When("query fails", func() {
+		BeforeEach(func() {
+			response = `{"exceptions": [{"errorCode": 150, "message": "SQLParsingError"}]}`
+		})
+
+		It("should report the exceptions", func() {
+			Expect(err).Should(MatchError("failed to query Pinot: 150: SQLParsingError"))
+		})
+	})
+
+})
+
+var _ = Describe("ProblemCycles", func() {
+	var (
+		server *httptest.Server
+		sql    string
+
+		cycles []pinot.ProblemCycle
+		err    error
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var req map[string]string
+			Expect(json.Unmarshal(body, &req)).Should(Succeed())
+			sql = req["sql"]
+			_, _ = w.Write([]byte(`{
+				"resultTable": {
+					"dataSchema": {"columnNames": ["objectRef.namespace", "objectRef.name", "expiration"]},
+					"rows": [["ns", "cert", 1640998800000000001], ["ns", "other", "1640998800000000002"]]
+				}
+			}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		client, clientErr := pinot.NewBrokerClient(pinot.Config{BrokerURL: server.URL})
+		Expect(clientErr).ShouldNot(HaveOccurred())
+		since := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		cycles, err = pinot.NewAlertHistory(client, "certsAlerts", nil).ProblemCycles(context.Background(), since)
+	})
+
+	It("should return the renewal cycles in WARN or ERROR", func() {
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(sql).Should(Equal(`SELECT "objectRef.namespace", "objectRef.name", "expiration" FROM certsAlerts ` +
+			`WHERE "level" IN ('WARN', 'ERROR') AND "when" >= 1640995200000000000 ` +
+			`GROUP BY "objectRef.namespace", "objectRef.name", "expiration" LIMIT 10000`))
+		Expect(cycles).Should(Equal([]pinot.ProblemCycle{
+			{Namespace: "ns", Name: "cert", Expiration: 1640998800000000001},
+			{Namespace: "ns", Name: "other", Expiration: 1640998800000000002},
+		}))
+	})
+})
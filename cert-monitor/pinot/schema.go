@@ -0,0 +1,236 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+)
+
+// Schema is the Pinot schema of a table
+type Schema struct {
+	// SchemaName is the name of the schema
+	SchemaName string `json:"schemaName"`
+	// DimensionFieldSpecs defines the dimension columns
+	DimensionFieldSpecs []FieldSpec `json:"dimensionFieldSpecs,omitempty"`
+	// MetricFieldSpecs defines the metric columns
+	MetricFieldSpecs []FieldSpec `json:"metricFieldSpecs,omitempty"`
+	// DateTimeFieldSpecs defines the date time columns
+	DateTimeFieldSpecs []DateTimeFieldSpec `json:"dateTimeFieldSpecs,omitempty"`
+}
+
+// FieldSpec defines a dimension or a metric column
+type FieldSpec struct {
+	// Name is the name of the column
+	Name string `json:"name"`
+	// DataType is the Pinot data type of the column, e.g. STRING or LONG
+	DataType string `json:"dataType"`
+	// SingleValueField is false for the multi-value columns, the column is single-valued when not set
+	SingleValueField *bool `json:"singleValueField,omitempty"`
+}
+
+// DateTimeFieldSpec defines a date time column
+type DateTimeFieldSpec struct {
+	// Name is the name of the column
+	Name string `json:"name"`
+	// DataType is the Pinot data type of the column
+	DataType string `json:"dataType"`
+	// Format is the format of the values, e.g. 1:NANOSECONDS:EPOCH
+	Format string `json:"format"`
+	// Granularity is the granularity of the values, e.g. 1:MINUTES
+	Granularity string `json:"granularity"`
+}
+
+// TimeColumn returns the name of the first date time column, the empty string when there is none
+func (s Schema) TimeColumn() string {
+	if len(s.DateTimeFieldSpecs) == 0 {
+		return ""
+	}
+	return s.DateTimeFieldSpecs[0].Name
+}
+
+// AlertSchema returns the schema of the alerts derived from alert.Alert
+func AlertSchema(name string) (Schema, error) {
+	return SchemaFromType(name, reflect.TypeOf(alert.Alert{}))
+}
+
+// WithWireFormat returns the schema of the records produced with the wire format, the columns are renamed and the
+// static fields are added as STRING dimensions
+func (s Schema) WithWireFormat(format alert.WireFormatConfig) Schema {
+	rename := func(name string) string {
+		if to, found := format.Rename[name]; found {
+			return to
+		}
+		return name
+	}
+	schema := Schema{SchemaName: s.SchemaName}
+	for _, field := range s.DimensionFieldSpecs {
+		field.Name = rename(field.Name)
+		schema.DimensionFieldSpecs = append(schema.DimensionFieldSpecs, field)
+	}
+	for _, field := range s.MetricFieldSpecs {
+		field.Name = rename(field.Name)
+		schema.MetricFieldSpecs = append(schema.MetricFieldSpecs, field)
+	}
+	for _, field := range s.DateTimeFieldSpecs {
+		field.Name = rename(field.Name)
+		schema.DateTimeFieldSpecs = append(schema.DateTimeFieldSpecs, field)
+	}
+	static := make([]string, 0, len(format.Fields))
+	for name := range format.Fields {
+		static = append(static, name)
+	}
+	sort.Strings(static)
+	for _, name := range static {
+		schema.DimensionFieldSpecs = append(schema.DimensionFieldSpecs, FieldSpec{Name: name, DataType: "STRING"})
+	}
+	return schema
+}
+
+// WithMapKeys returns the schema with a STRING dimension for each key of the map field, the maps being flattened when
+// ingested, the columns are named after the field and the key joined with a dot, e.g. labels.team
+func (s Schema) WithMapKeys(field string, keys []string) Schema {
+	schema := s
+	schema.DimensionFieldSpecs = append([]FieldSpec(nil), s.DimensionFieldSpecs...)
+	for _, key := range keys {
+		schema.DimensionFieldSpecs = append(schema.DimensionFieldSpecs, FieldSpec{Name: field + "." + key, DataType: "STRING"})
+	}
+	return schema
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaFromType derives a schema from a struct type. The columns are named after the json tags of the fields, the
+// fields of the nested structs are flattened with a dot, e.g. objectRef.name, and the slices are multi-value columns.
+// The pinot tag defines the type of the column: dimension by default, metric, or dateTime followed by the format and
+// the granularity, e.g. `pinot:"dateTime,1:NANOSECONDS:EPOCH,1:MINUTES"`. The fields tagged with `pinot:"-"` are
+// skipped.
+func SchemaFromType(name string, t reflect.Type) (Schema, error) {
+	if t.Kind() != reflect.Struct {
+		return Schema{}, fmt.Errorf("failed to derive schema: %s is not a struct", t)
+	}
+	schema := Schema{SchemaName: name}
+	if err := schema.addFields("", t); err != nil {
+		return Schema{}, fmt.Errorf("failed to derive schema: %w", err)
+	}
+	return schema, nil
+}
+
+func (s *Schema) addFields(prefix string, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		pinotTag := field.Tag.Get("pinot")
+		if jsonName == "-" || pinotTag == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		column := prefix + jsonName
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && !isMarshaler(fieldType) {
+			if err := s.addFields(column+".", fieldType); err != nil {
+				return err
+			}
+			continue
+		}
+		var singleValue *bool
+		if fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+			singleValue = new(bool)
+		}
+		dataType, err := dataTypeOf(fieldType)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", column, err)
+		}
+
+		options := strings.Split(pinotTag, ",")
+		switch options[0] {
+		case "", "dimension":
+			s.DimensionFieldSpecs = append(s.DimensionFieldSpecs, FieldSpec{Name: column, DataType: dataType, SingleValueField: singleValue})
+		case "metric":
+			if singleValue != nil {
+				return fmt.Errorf("field %s: metric cannot be multi-valued", column)
+			}
+			s.MetricFieldSpecs = append(s.MetricFieldSpecs, FieldSpec{Name: column, DataType: dataType})
+		case "dateTime":
+			if singleValue != nil {
+				return fmt.Errorf("field %s: dateTime cannot be multi-valued", column)
+			}
+			if len(options) != 3 {
+				return fmt.Errorf("field %s: dateTime expects a format and a granularity", column)
+			}
+			s.DateTimeFieldSpecs = append(s.DateTimeFieldSpecs, DateTimeFieldSpec{
+				Name:        column,
+				DataType:    dataType,
+				Format:      options[1],
+				Granularity: options[2],
+			})
+		default:
+			return fmt.Errorf("field %s: unknown field type %q", column, options[0])
+		}
+	}
+	return nil
+}
+
+// isMarshaler returns true when the values of the type are marshaled as JSON strings by a custom marshaler
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+func dataTypeOf(t reflect.Type) (string, error) {
+	if isMarshaler(t) {
+		return "STRING", nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "STRING", nil
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "INT", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "LONG", nil
+	case reflect.Float32:
+		return "FLOAT", nil
+	case reflect.Float64:
+		return "DOUBLE", nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", t)
+	}
+}
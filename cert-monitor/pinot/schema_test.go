@@ -0,0 +1,142 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot_test
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schema", func() {
+	It("should derive the schema of the alerts", func() {
+		schema, err := pinot.AlertSchema("certsAlerts")
+		Expect(err).ShouldNot(HaveOccurred())
+		data, err := json.Marshal(schema)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).Should(MatchJSON(`{
+			"schemaName": "certsAlerts",
+			"dimensionFieldSpecs": [
+				{"name": "level", "dataType": "STRING"},
+				{"name": "message", "dataType": "STRING"},
+				{"name": "objectRef.name", "dataType": "STRING"},
+				{"name": "objectRef.namespace", "dataType": "STRING"},
+				{"name": "objectRef.kind", "dataType": "STRING"},
+				{"name": "objectRef.apiVersion", "dataType": "STRING"},
+				{"name": "objectRef.uid", "dataType": "STRING"},
+				{"name": "source", "dataType": "STRING"},
+				{"name": "certificate.issuerName", "dataType": "STRING"},
+				{"name": "certificate.issuerKind", "dataType": "STRING"},
+				{"name": "certificate.secretName", "dataType": "STRING"},
+				{"name": "certificate.dnsNames", "dataType": "STRING", "singleValueField": false},
+				{"name": "certificate.serialNumber", "dataType": "STRING"},
+				{"name": "certificate.fingerprintSha256", "dataType": "STRING"},
+				{"name": "certificate.keyAlgorithm", "dataType": "STRING"},
+				{"name": "certificate.keySize", "dataType": "LONG"},
+				{"name": "certificate.notBefore", "dataType": "LONG"},
+				{"name": "certificate.notAfter", "dataType": "LONG"}
+			],
+			"metricFieldSpecs": [
+				{"name": "expiration", "dataType": "LONG"},
+				{"name": "remainingSeconds", "dataType": "LONG"}
+			],
+			"dateTimeFieldSpecs": [
+				{"name": "when", "dataType": "LONG", "format": "1:NANOSECONDS:EPOCH", "granularity": "1:MINUTES"}
+			]
+		}`))
+		Expect(schema.TimeColumn()).Should(Equal("when"))
+	})
+
+	It("should apply the wire format", func() {
+		schema, err := pinot.AlertSchema("certsAlerts")
+		Expect(err).ShouldNot(HaveOccurred())
+		schema = schema.WithWireFormat(alert.WireFormatConfig{
+			Rename: map[string]string{"objectRef.name": "certificate", "when": "timestamp"},
+			Fields: map[string]string{"environment": "prod", "cluster": "eu-1"},
+		})
+		Expect(schema.DimensionFieldSpecs).Should(ContainElement(pinot.FieldSpec{Name: "certificate", DataType: "STRING"}))
+		Expect(schema.DimensionFieldSpecs).ShouldNot(ContainElement(pinot.FieldSpec{Name: "objectRef.name", DataType: "STRING"}))
+		Expect(schema.DimensionFieldSpecs[len(schema.DimensionFieldSpecs)-2:]).Should(Equal([]pinot.FieldSpec{
+			{Name: "cluster", DataType: "STRING"},
+			{Name: "environment", DataType: "STRING"},
+		}))
+		Expect(schema.TimeColumn()).Should(Equal("timestamp"))
+	})
+
+	It("should add the keys of the maps", func() {
+		schema, err := pinot.AlertSchema("certsAlerts")
+		Expect(err).ShouldNot(HaveOccurred())
+		size := len(schema.DimensionFieldSpecs)
+		withLabels := schema.WithMapKeys("labels", []string{"env", "team"})
+		Expect(withLabels.DimensionFieldSpecs[size:]).Should(Equal([]pinot.FieldSpec{
+			{Name: "labels.env", DataType: "STRING"},
+			{Name: "labels.team", DataType: "STRING"},
+		}))
+		Expect(schema.DimensionFieldSpecs).Should(HaveLen(size))
+	})
+
+	It("should skip the fields tagged with -", func() {
+		type record struct {
+			Name    string            `json:"name"`
+			Count   int32             `json:"count" pinot:"metric"`
+			Ratio   float64           `json:"ratio" pinot:"metric"`
+			Enabled bool              `json:"enabled"`
+			Labels  map[string]string `json:"labels" pinot:"-"`
+			Ignored string            `json:"-"`
+		}
+		schema, err := pinot.SchemaFromType("records", reflect.TypeOf(record{}))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(schema.DimensionFieldSpecs).Should(Equal([]pinot.FieldSpec{
+			{Name: "name", DataType: "STRING"},
+			{Name: "enabled", DataType: "BOOLEAN"},
+		}))
+		Expect(schema.MetricFieldSpecs).Should(Equal([]pinot.FieldSpec{
+			{Name: "count", DataType: "INT"},
+			{Name: "ratio", DataType: "DOUBLE"},
+		}))
+		Expect(schema.DateTimeFieldSpecs).Should(BeEmpty())
+	})
+
+	It("should reject the unsupported types", func() {
+		type record struct {
+			Labels map[string]string `json:"labels"`
+		}
+		_, err := pinot.SchemaFromType("records", reflect.TypeOf(record{}))
+		Expect(err).Should(MatchError(ContainSubstring("field labels: unsupported type map[string]string")))
+	})
+
+	It("should reject a dateTime without format", func() {
+		type record struct {
+			When int64 `json:"when" pinot:"dateTime"`
+		}
+		_, err := pinot.SchemaFromType("records", reflect.TypeOf(record{}))
+		Expect(err).Should(MatchError(ContainSubstring("dateTime expects a format and a granularity")))
+	})
+
+	It("should reject a type which is not a struct", func() {
+		_, err := pinot.SchemaFromType("records", reflect.TypeOf(alert.Info))
+		Expect(err).Should(HaveOccurred())
+	})
+})
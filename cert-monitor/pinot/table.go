@@ -0,0 +1,161 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+)
+
+const (
+	jsonDecoder          = "org.apache.pinot.plugin.stream.kafka.KafkaJSONMessageDecoder"
+	avroDecoder          = "org.apache.pinot.plugin.inputformat.avro.confluent.KafkaConfluentSchemaRegistryAvroMessageDecoder"
+	kafkaConsumerFactory = "org.apache.pinot.plugin.stream.kafka20.KafkaConsumerFactory"
+)
+
+// TableConfig is the configuration of a Pinot table
+type TableConfig struct {
+	// TableName is the name of the table with its type, e.g. certsAlerts_REALTIME
+	TableName string `json:"tableName"`
+	// TableType is the type of the table, OFFLINE or REALTIME
+	TableType string `json:"tableType"`
+	// SegmentsConfig defines the time column, the retention and the replication of the segments
+	SegmentsConfig SegmentsConfig `json:"segmentsConfig"`
+	// Tenants defines the tenants of the table, the default tenants are used when empty
+	Tenants map[string]string `json:"tenants"`
+	// TableIndexConfig defines the indexes and the stream consumed by a REALTIME table
+	TableIndexConfig TableIndexConfig `json:"tableIndexConfig"`
+	// IngestionConfig defines how the records are transformed before being ingested
+	IngestionConfig IngestionConfig `json:"ingestionConfig"`
+}
+
+// SegmentsConfig defines the time column, the retention and the replication of the segments
+type SegmentsConfig struct {
+	SchemaName                string `json:"schemaName"`
+	TimeColumnName            string `json:"timeColumnName"`
+	RetentionTimeUnit         string `json:"retentionTimeUnit"`
+	RetentionTimeValue        string `json:"retentionTimeValue"`
+	SegmentPushType           string `json:"segmentPushType"`
+	SegmentAssignmentStrategy string `json:"segmentAssignmentStrategy"`
+	Replication               string `json:"replication"`
+	ReplicasPerPartition      string `json:"replicasPerPartition"`
+}
+
+// TableIndexConfig defines the indexes and the stream consumed by a REALTIME table
+type TableIndexConfig struct {
+	LoadMode      string            `json:"loadMode"`
+	StreamConfigs map[string]string `json:"streamConfigs,omitempty"`
+}
+
+// IngestionConfig defines how the records are transformed before being ingested
+type IngestionConfig struct {
+	ComplexTypeConfig ComplexTypeConfig `json:"complexTypeConfig"`
+}
+
+// ComplexTypeConfig defines how the nested records are flattened
+type ComplexTypeConfig struct {
+	// Delimiter joins the names of the nested fields, e.g. objectRef.name
+	Delimiter string `json:"delimiter"`
+}
+
+// RealtimeTableConfig returns the configuration of the REALTIME table consuming the alerts produced by the kafka
+// notifier. The nested fields of the alerts are flattened with a dot to match the columns of the schema.
+func RealtimeTableConfig(cfg Config, kafka alert.KafkaConfig, schema Schema) (TableConfig, error) {
+	cfg = cfg.WithDefaults()
+	if kafka.Topic == "" {
+		return TableConfig{}, errors.New("missing kafka topic")
+	}
+	if len(kafka.Brokers) == 0 {
+		return TableConfig{}, errors.New("missing kafka brokers")
+	}
+	if kafka.CloudEvents == alert.CloudEventsStructured {
+		return TableConfig{}, errors.New("structured CloudEvents are not supported, use binary CloudEvents instead")
+	}
+	if schema.TimeColumn() == "" {
+		return TableConfig{}, fmt.Errorf("schema %s has no date time column", schema.SchemaName)
+	}
+
+	streamConfigs := map[string]string{
+		"streamType":                                   "kafka",
+		"stream.kafka.consumer.type":                   "lowlevel",
+		"stream.kafka.topic.name":                      kafka.Topic,
+		"stream.kafka.broker.list":                     strings.Join(kafka.Brokers, ","),
+		"stream.kafka.consumer.factory.class.name":     kafkaConsumerFactory,
+		"stream.kafka.consumer.prop.auto.offset.reset": "smallest",
+		"realtime.segment.flush.threshold.time":        "3600000",
+		"realtime.segment.flush.threshold.size":        "50000",
+	}
+	switch kafka.Encoding {
+	case "", alert.EncodingJSON:
+		streamConfigs["stream.kafka.decoder.class.name"] = jsonDecoder
+	case alert.EncodingAvro:
+		if kafka.SchemaRegistry.URL == "" {
+			return TableConfig{}, errors.New("missing schema registry URL for the avro encoding")
+		}
+		streamConfigs["stream.kafka.decoder.class.name"] = avroDecoder
+		streamConfigs["stream.kafka.decoder.prop.schema.registry.rest.url"] = kafka.SchemaRegistry.URL
+	default:
+		return TableConfig{}, fmt.Errorf("encoding %q is not supported by the Pinot realtime table", kafka.Encoding)
+	}
+
+	table := tableConfig(cfg, "REALTIME", schema)
+	table.TableIndexConfig.StreamConfigs = streamConfigs
+	return table, nil
+}
+
+// OfflineTableConfig returns the configuration of the OFFLINE table where the pinot notifier ingests the alerts. It
+// shares the name and the schema of the REALTIME table, so the broker queries both as a hybrid table.
+func OfflineTableConfig(cfg Config, schema Schema) (TableConfig, error) {
+	cfg = cfg.WithDefaults()
+	if schema.TimeColumn() == "" {
+		return TableConfig{}, fmt.Errorf("schema %s has no date time column", schema.SchemaName)
+	}
+	return tableConfig(cfg, "OFFLINE", schema), nil
+}
+
+// tableConfig returns the configuration of the table of the type without stream
+func tableConfig(cfg Config, tableType string, schema Schema) TableConfig {
+	replication := strconv.Itoa(cfg.Replication)
+	return TableConfig{
+		TableName: cfg.Table + "_" + tableType,
+		TableType: tableType,
+		SegmentsConfig: SegmentsConfig{
+			SchemaName:                schema.SchemaName,
+			TimeColumnName:            schema.TimeColumn(),
+			RetentionTimeUnit:         "DAYS",
+			RetentionTimeValue:        strconv.Itoa(cfg.RetentionDays),
+			SegmentPushType:           "APPEND",
+			SegmentAssignmentStrategy: "BalanceNumSegmentAssignmentStrategy",
+			Replication:               replication,
+			ReplicasPerPartition:      replication,
+		},
+		Tenants: map[string]string{},
+		TableIndexConfig: TableIndexConfig{
+			LoadMode: "MMAP",
+		},
+		IngestionConfig: IngestionConfig{
+			ComplexTypeConfig: ComplexTypeConfig{Delimiter: "."},
+		},
+	}
+}
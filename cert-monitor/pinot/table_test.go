@@ -0,0 +1,177 @@
+// Copyright (c) 2022 Denis Vergnes
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package pinot_test
+
+import (
+	"encoding/json"
+
+	"github.com/dvergnes/pinot-playground/cert-monitor/alert"
+	"github.com/dvergnes/pinot-playground/cert-monitor/pinot"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RealtimeTableConfig", func() {
+	var (
+		cfg    pinot.Config
+		kafka  alert.KafkaConfig
+		schema pinot.Schema
+		table  pinot.TableConfig
+		err    error
+	)
+
+	BeforeEach(func() {
+		cfg = pinot.Config{}
+		kafka = alert.KafkaConfig{
+			Topic:   "cert-monitor-alerts",
+			Brokers: []string{"kafka-0:9092", "kafka-1:9092"},
+		}
+		schema, err = pinot.AlertSchema(pinot.DefaultTable)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		table, err = pinot.RealtimeTableConfig(cfg, kafka, schema)
+	})
+
+	When("alerts are encoded in JSON", func() {
+		It("should consume the topic with the JSON decoder", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			data, err := json.Marshal(table)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(data).Should(MatchJSON(`{
+				"tableName": "certsAlerts_REALTIME",
+				"tableType": "REALTIME",
+				"segmentsConfig": {
+					"schemaName": "certsAlerts",
+					"timeColumnName": "when",
+					"retentionTimeUnit": "DAYS",
+					"retentionTimeValue": "10",
+					"segmentPushType": "APPEND",
+					"segmentAssignmentStrategy": "BalanceNumSegmentAssignmentStrategy",
+					"replication": "1",
+					"replicasPerPartition": "1"
+				},
+				"tenants": {},
+				"tableIndexConfig": {
+					"loadMode": "MMAP",
+					"streamConfigs": {
+						"streamType": "kafka",
+						"stream.kafka.consumer.type": "lowlevel",
+						"stream.kafka.topic.name": "cert-monitor-alerts",
+						"stream.kafka.broker.list": "kafka-0:9092,kafka-1:9092",
+						"stream.kafka.consumer.factory.class.name": "org.apache.pinot.plugin.stream.kafka20.KafkaConsumerFactory",
+						"stream.kafka.consumer.prop.auto.offset.reset": "smallest",
+						"stream.kafka.decoder.class.name": "org.apache.pinot.plugin.stream.kafka.KafkaJSONMessageDecoder",
+						"realtime.segment.flush.threshold.time": "3600000",
+						"realtime.segment.flush.threshold.size": "50000"
+					}
+				},
+				"ingestionConfig": {
+					"complexTypeConfig": {"delimiter": "."}
+				}
+			}`))
+		})
+	})
+
+	When("table is configured", func() {
+		BeforeEach(func() {
+			cfg = pinot.Config{Table: "alerts", Replication: 3, RetentionDays: 30}
+			schema.SchemaName = "alerts"
+		})
+
+		It("should apply the configuration", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(table.TableName).Should(Equal("alerts_REALTIME"))
+			Expect(table.SegmentsConfig.SchemaName).Should(Equal("alerts"))
+			Expect(table.SegmentsConfig.Replication).Should(Equal("3"))
+			Expect(table.SegmentsConfig.ReplicasPerPartition).Should(Equal("3"))
+			Expect(table.SegmentsConfig.RetentionTimeValue).Should(Equal("30"))
+		})
+	})
+
+	When("alerts are encoded in avro", func() {
+		BeforeEach(func() {
+			kafka.Encoding = alert.EncodingAvro
+			kafka.SchemaRegistry.URL = "http://schema-registry:8081"
+		})
+
+		It("should consume the topic with the schema registry decoder", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(table.TableIndexConfig.StreamConfigs).Should(And(
+				HaveKeyWithValue("stream.kafka.decoder.class.name", "org.apache.pinot.plugin.inputformat.avro.confluent.KafkaConfluentSchemaRegistryAvroMessageDecoder"),
+				HaveKeyWithValue("stream.kafka.decoder.prop.schema.registry.rest.url", "http://schema-registry:8081"),
+			))
+		})
+	})
+
+	When("alerts are encoded in protobuf", func() {
+		BeforeEach(func() {
+			kafka.Encoding = alert.EncodingProtobuf
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError(ContainSubstring(`encoding "protobuf" is not supported`)))
+		})
+	})
+
+	When("alerts are wrapped in structured CloudEvents", func() {
+		BeforeEach(func() {
+			kafka.CloudEvents = alert.CloudEventsStructured
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError(ContainSubstring("structured CloudEvents are not supported")))
+		})
+	})
+
+	When("brokers are missing", func() {
+		BeforeEach(func() {
+			kafka.Brokers = nil
+		})
+
+		It("should fail", func() {
+			Expect(err).Should(MatchError("missing kafka brokers"))
+		})
+	})
+})
+
+var _ = Describe("OfflineTableConfig", func() {
+	It("should share the name and the schema of the REALTIME table without stream", func() {
+		schema, err := pinot.AlertSchema("alerts")
+		Expect(err).ShouldNot(HaveOccurred())
+		table, err := pinot.OfflineTableConfig(pinot.Config{Table: "alerts", Replication: 2}, schema)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(table.TableName).Should(Equal("alerts_OFFLINE"))
+		Expect(table.TableType).Should(Equal("OFFLINE"))
+		Expect(table.SegmentsConfig.SchemaName).Should(Equal("alerts"))
+		Expect(table.SegmentsConfig.TimeColumnName).Should(Equal("when"))
+		Expect(table.SegmentsConfig.Replication).Should(Equal("2"))
+		data, err := json.Marshal(table.TableIndexConfig)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).Should(MatchJSON(`{"loadMode": "MMAP"}`))
+	})
+
+	It("should fail without date time column", func() {
+		_, err := pinot.OfflineTableConfig(pinot.Config{}, pinot.Schema{SchemaName: "alerts"})
+		Expect(err).Should(MatchError("schema alerts has no date time column"))
+	})
+})